@@ -11,7 +11,9 @@ import (
 	"reactor/models"
 	"reactor/types"
 	"reactor/utils"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	dockertypes "github.com/docker/docker/api/types"
@@ -24,7 +26,6 @@ import (
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/dustin/go-humanize"
-	"github.com/gin-gonic/gin"
 	"github.com/zishang520/socket.io/socket"
 )
 
@@ -32,13 +33,20 @@ var app *App
 
 type App struct {
 	connectionManager  *models.ConnectionManager
+	statsManager       *models.StatsManager
+	registryManager    *models.RegistryManager
 	configManager      *utils.ConfigurationManager
+	instanceID         string
 	client             *client.Client
 	SocketServer       *socket.Server
 	Logger             *log.Logger
 	Subscribers        map[string]*types.Subscriber
 	AttachedContainers map[string]*dockertypes.HijackedResponse
 	AttachedExecs      map[string]*dockertypes.HijackedResponse
+	detachKeys         map[string][]byte
+	jobs               map[string]*jobEntry
+	layerAnalyses      map[string]*layerAnalysis
+	lock               sync.Mutex
 }
 
 func DefaultApp() *App {
@@ -73,6 +81,7 @@ func (app *App) Setup() {
 func (app *App) setupDockerClient() {
 	app.AttachedContainers = make(map[string]*dockertypes.HijackedResponse)
 	app.AttachedExecs = make(map[string]*dockertypes.HijackedResponse)
+	app.detachKeys = make(map[string][]byte)
 
 	cm := app.connectionManager
 	_, ds := cm.GetDefaultConnection()
@@ -210,12 +219,20 @@ func (app *App) beforeInitHooks() {
 	app.initDb()
 }
 func (app *App) afterInitHooks() {
+	// synchronous, so the sweep is over before any request can create helpers
+	app.removeVolumeHelpers()
 	go app.SetupDaemonEventListeners()
+	go app.runStatsSampler()
 	app.SetupAppEventListeners()
 }
 func (app *App) initDefaultSettings() {
 	app.configManager = utils.DefaultConfigurationManager()
 	app.configManager.InitDefaults()
+	id, err := app.configManager.GetInstanceID()
+	if err != nil {
+		app.Logger.Println("cannot read instance id:", err.Error())
+	}
+	app.instanceID = id
 }
 func (app *App) initLogger() {
 	logger := log.New(os.Stdout, fmt.Sprintf("[%s]: ", utils.APP_NAME), log.LstdFlags)
@@ -225,6 +242,10 @@ func (app *App) initLogger() {
 func (app *App) initDb() {
 	app.connectionManager = models.DefaultConnectionManager()
 	app.connectionManager.InitDefaults()
+	app.statsManager = models.DefaultStatsManager()
+	app.statsManager.InitDefaults()
+	app.registryManager = models.DefaultRegistryManager()
+	app.registryManager.InitDefaults()
 }
 func (app *App) SetupAppEventListeners() {}
 func (app *App) SetupDaemonEventListeners() {
@@ -516,6 +537,9 @@ func (app *App) ContainerList() []*types.ContainerSummary {
 		return csummary
 	}
 	for _, container := range containers {
+		if isVolumeHelper(&container) {
+			continue
+		}
 		csummary = append(csummary, &types.ContainerSummary{
 			ID:      container.ID,
 			Name:    strings.ReplaceAll(container.Names[0], "/", ""),
@@ -592,12 +616,29 @@ func (app *App) VolumeList() []types.VolumeSummary {
 	if err != nil {
 		return vsummary
 	}
+	// the volumes are still listed when their users cannot be looked up
+	users, err := app.volumeContainers()
+	if err != nil {
+		app.Logger.Println("cannot list volume users:", err.Error())
+	}
 	for _, volume := range volumes.Volumes {
+		labels := volume.Labels
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		containers := users[volume.Name]
+		if containers == nil {
+			containers = make([]types.VolumeContainer, 0)
+		}
 		vsummary = append(vsummary, types.VolumeSummary{
 			ID:         volume.Name,
 			Name:       volume.Name,
 			Created:    volume.CreatedAt,
 			MountPoint: volume.Mountpoint,
+			Driver:     volume.Driver,
+			Scope:      volume.Scope,
+			Labels:     labels,
+			Containers: containers,
 		})
 	}
 	return vsummary
@@ -609,12 +650,49 @@ func (app *App) NetworkList() []types.NetworkSummary {
 	if err != nil {
 		return nsummary
 	}
-	for _, network := range networks {
+	for _, n := range networks {
+		// the list leaves out attached containers, only inspect has them. A
+		// network that cannot be inspected is still listed, without them.
+		inspected, err := cli.NetworkInspect(context.Background(), n.ID, network.InspectOptions{})
+		if err != nil {
+			app.Logger.Println("cannot inspect network:", err.Error())
+		} else {
+			n = inspected
+		}
+		subnets := make([]types.NetworkSubnet, 0, len(n.IPAM.Config))
+		for _, c := range n.IPAM.Config {
+			subnets = append(subnets, types.NetworkSubnet{
+				Subnet:  c.Subnet,
+				Gateway: c.Gateway,
+			})
+		}
+		containers := make([]types.NetworkContainer, 0, len(n.Containers))
+		for id, c := range n.Containers {
+			// addresses are reported with the subnet prefix length
+			ipv4, _, _ := strings.Cut(c.IPv4Address, "/")
+			ipv6, _, _ := strings.Cut(c.IPv6Address, "/")
+			containers = append(containers, types.NetworkContainer{
+				ID:          id,
+				Name:        c.Name,
+				IPv4Address: ipv4,
+				IPv6Address: ipv6,
+				MacAddress:  c.MacAddress,
+			})
+		}
+		sort.Slice(containers, func(i, j int) bool {
+			return containers[i].Name < containers[j].Name
+		})
 		nsummary = append(nsummary, types.NetworkSummary{
-			ID:      network.ID,
-			Name:    network.Name,
-			Created: network.Created.Format(time.UnixDate),
-			Ports:   []string{":80/tcp"},
+			ID:         n.ID,
+			Name:       n.Name,
+			Created:    n.Created.Format(time.UnixDate),
+			Driver:     n.Driver,
+			Scope:      n.Scope,
+			Internal:   n.Internal,
+			Attachable: n.Attachable,
+			EnableIPv6: n.EnableIPv6,
+			Subnets:    subnets,
+			Containers: containers,
 		})
 	}
 	return nsummary
@@ -647,6 +725,11 @@ func (app *App) ContainerCreate(params *types.ContainerCreateParams) (*types.Con
 		cfg.Env = env
 	}
 
+	exposedPorts, hostConfig, networkingConfig, err := buildHostConfig(params)
+	if err != nil {
+		return nil, err
+	}
+
 	res, err := app.client.ContainerCreate(context.Background(), &container.Config{
 		Image:        params.Image,
 		AttachStdin:  params.Stdin,
@@ -660,7 +743,9 @@ func (app *App) ContainerCreate(params *types.ContainerCreateParams) (*types.Con
 		User:         params.User,
 		Shell:        params.Shell,
 		WorkingDir:   params.WorkingDir,
-	}, nil, nil, nil, name)
+		Labels:       params.Labels,
+		ExposedPorts: exposedPorts,
+	}, hostConfig, networkingConfig, nil, name)
 	if err != nil {
 		return nil, err
 	}
@@ -728,19 +813,26 @@ func (app *App) ContainerTop(params *types.ContainerTopParams) (container.Contai
 	top, err := app.client.ContainerTop(context.Background(), params.ID, []string{})
 	return top, err
 }
-func (app *App) ContainerStats(params *types.ContainerStatsParams) (string, error) {
-	res, err := app.client.ContainerStatsOneShot(context.Background(), params.ID)
+
+// ContainerPutArchive extracts a tar archive, optionally compressed, into the
+// directory at query.Path inside the container.
+func (app *App) ContainerPutArchive(params *types.ContainerRequestParams, query *types.ContainerArchiveQuery, content io.Reader) error {
+	return app.client.CopyToContainer(context.Background(), params.ID, query.Path, content, container.CopyToContainerOptions{
+		AllowOverwriteDirWithFile: query.AllowOverwriteDirNonDir,
+		CopyUIDGID:                query.CopyUIDGID,
+	})
+}
+
+// ContainerGetArchive returns a tar stream of the file or directory at
+// query.Path inside the container together with its stat. The caller must
+// close the stream.
+func (app *App) ContainerGetArchive(params *types.ContainerRequestParams, query *types.ContainerArchiveQuery) (io.ReadCloser, *container.PathStat, error) {
+	rc, stat, err := app.client.CopyFromContainer(context.Background(), params.ID, query.Path)
 	if err != nil {
-		fmt.Println("[stats#error]:", err.Error())
+		return nil, nil, err
 	}
-	buf := new(strings.Builder)
-	n, err := io.Copy(buf, res.Body)
-	body := buf.String()
-	fmt.Println("[stats]:", n, body)
-	return body, err
+	return rc, &stat, nil
 }
-func (app *App) ContainerPutArchive(params *types.ContainerRequestParams) {}
-func (app *App) ContainerGetArchive(params *types.ContainerRequestParams) {}
 func (app *App) ContainerRename(params *types.ContainerRequestParams, body *types.ContainerRenameParams) error {
 	err := app.client.ContainerRename(context.Background(), params.ID, body.NewName)
 	return err
@@ -749,6 +841,63 @@ func (app *App) ContainerRemove(params *types.ContainerRemoveParams) error {
 	err := app.client.ContainerRemove(context.Background(), params.ID, container.RemoveOptions{})
 	return err
 }
+
+// instructions `docker commit --change` accepts
+var commitInstructions = map[string]bool{
+	"CMD":        true,
+	"ENTRYPOINT": true,
+	"ENV":        true,
+	"EXPOSE":     true,
+	"LABEL":      true,
+	"ONBUILD":    true,
+	"USER":       true,
+	"VOLUME":     true,
+	"WORKDIR":    true,
+}
+
+// checkChanges rejects Dockerfile instructions the daemon cannot apply to a
+// committed or imported image.
+func checkChanges(changes []string) error {
+	for i, change := range changes {
+		instruction, _, _ := strings.Cut(strings.TrimSpace(change), " ")
+		if !commitInstructions[strings.ToUpper(instruction)] {
+			return fmt.Errorf("changes[%d]: unsupported instruction %q", i, instruction)
+		}
+	}
+	return nil
+}
+
+// ContainerCommit snapshots the container filesystem and config into a new
+// image and returns the image ID. The container is paused while committing
+// unless body.Pause is explicitly false.
+func (app *App) ContainerCommit(params *types.ContainerRequestParams, body *types.ContainerCommitParams) (string, error) {
+	if body.Tag != "" && body.Repo == "" {
+		return "", fmt.Errorf("tag: requires repo to be set")
+	}
+	err := checkChanges(body.Changes)
+	if err != nil {
+		return "", err
+	}
+	ref := body.Repo
+	if ref != "" && body.Tag != "" {
+		ref = fmt.Sprintf("%s:%s", body.Repo, body.Tag)
+	}
+	pause := true
+	if body.Pause != nil {
+		pause = *body.Pause
+	}
+	res, err := app.client.ContainerCommit(context.Background(), params.ID, container.CommitOptions{
+		Reference: ref,
+		Comment:   body.Message,
+		Author:    body.Author,
+		Changes:   body.Changes,
+		Pause:     pause,
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
 func (app *App) ContainerInspect(params *types.ContainerRequestParams) (*dockertypes.ContainerJSON, error) {
 	cjson, err := app.client.ContainerInspect(context.Background(), params.ID)
 	if err != nil {
@@ -756,40 +905,176 @@ func (app *App) ContainerInspect(params *types.ContainerRequestParams) (*dockert
 	}
 	return &cjson, nil
 }
-func (app *App) ContainerAttach(params *types.ContainerAttachParams) error {
+
+// ContainerAttach attaches to the main process of a container and forwards its
+// demultiplexed output to client as "attach_output" frames. A previous
+// attachment to the same container is detached first.
+func (app *App) ContainerAttach(client *socket.Socket, params *types.ContainerAttachParams) error {
+	detachKeys := params.DetachKeys
+	if detachKeys == "" {
+		detachKeys = utils.DEFAULT_DETACH_KEYS
+	}
+	keys, err := utils.ParseDetachKeys(detachKeys)
+	if err != nil {
+		return err
+	}
+	cjson, err := app.client.ContainerInspect(context.Background(), params.ID)
+	if err != nil {
+		return err
+	}
+	if !params.Stdin && !params.Stdout && !params.Stderr {
+		params.Stdout = true
+		params.Stderr = true
+		params.Stream = true
+	}
+	app.ContainerDetach(params.ID)
+
 	hj, err := app.client.ContainerAttach(context.Background(), params.ID, container.AttachOptions{
-		Stream: params.Stream,
-		Stdin:  params.Stdin,
-		Stdout: params.Stdout,
-		Stderr: params.Stderr,
-		Logs:   params.Logs,
+		Stream:     params.Stream,
+		Stdin:      params.Stdin,
+		Stdout:     params.Stdout,
+		Stderr:     params.Stderr,
+		Logs:       params.Logs,
+		DetachKeys: detachKeys,
 	})
+	if err != nil {
+		return err
+	}
+	app.lock.Lock()
 	app.AttachedContainers[params.ID] = &hj
+	app.detachKeys[params.ID] = keys
+	app.lock.Unlock()
+
+	go func() {
+		stdout := newSocketWriter(client, "attach_output", params.ID, "stdout")
+		stderr := newSocketWriter(client, "attach_output", params.ID, "stderr")
+		err := demuxStream(hj.Reader, cjson.Config != nil && cjson.Config.Tty, stdout, stderr)
+		if err != nil {
+			fmt.Println("[attach#stream]:", err.Error())
+		}
+		app.lock.Lock()
+		if app.AttachedContainers[params.ID] == &hj {
+			delete(app.AttachedContainers, params.ID)
+			delete(app.detachKeys, params.ID)
+		}
+		app.lock.Unlock()
+		hj.Close()
+		client.Emit("detached", params.ID)
+	}()
+	return nil
+}
+func (app *App) ContainerAttachInput(params *types.ContainerAttachInputParams) error {
+	app.lock.Lock()
+	hj := app.AttachedContainers[params.ID]
+	app.lock.Unlock()
+	if hj == nil {
+		return dockertypes.ErrorResponse{
+			Message: "container is not attached",
+		}
+	}
+	_, err := hj.Conn.Write([]byte(params.Data))
 	return err
 }
-func (app *App) ContainerLogs(params *types.ContainerLogsParams, opts *types.ContainerLogsQuery) (string, error) {
-	r, err := app.client.ContainerLogs(context.Background(), params.ID, container.LogsOptions{
-		ShowStdout: true,
-		ShowStderr: true,
-		Follow:     false,
-		/* ShowStdout: params.ShowStdout,
-		ShowStderr: params.ShowStderr,
-		Details:    params.Details,
-		Timestamps: params.Timestamps,
-		Tail:       params.Tail,
-		Follow:     params.Follow,
-		Since:      params.Since,
-		Until:      params.Until, */
+
+// ContainerDetach sends the detach key sequence so the daemon ends the attach
+// session without signalling the container, then closes the connection.
+func (app *App) ContainerDetach(id string) bool {
+	app.lock.Lock()
+	hj := app.AttachedContainers[id]
+	keys := app.detachKeys[id]
+	delete(app.AttachedContainers, id)
+	delete(app.detachKeys, id)
+	app.lock.Unlock()
+	if hj == nil {
+		return false
+	}
+	if len(keys) > 0 {
+		hj.Conn.Write(keys)
+	}
+	hj.Close()
+	return true
+}
+
+// ContainerLogs reads the container logs selected by opts and returns them as
+// frames tagged with the stream they were written to. opts.Follow is ignored,
+// use ContainerLogsFollow to keep reading.
+func (app *App) ContainerLogs(params *types.ContainerLogsParams, opts *types.ContainerLogsQuery) ([]*types.StreamFrame, error) {
+	logOpts := *opts
+	logOpts.Follow = false
+	r, tty, err := app.containerLogsReader(context.Background(), params.ID, &logOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	frames := make([]*types.StreamFrame, 0)
+	collect := func(frame *types.StreamFrame) {
+		frames = append(frames, frame)
+	}
+	err = demuxStream(r, tty, &frameWriter{id: params.ID, stream: "stdout", emit: collect}, &frameWriter{id: params.ID, stream: "stderr", emit: collect})
+	return frames, err
+}
+
+// ContainerLogsFollow opens the log stream of a container and sends each frame
+// to the frames channel until ctx is cancelled or the container stops. The
+// channel is closed when the stream ends. Errors opening the stream are
+// returned before anything is sent.
+func (app *App) ContainerLogsFollow(ctx context.Context, params *types.ContainerLogsParams, opts *types.ContainerLogsQuery, frames chan<- *types.StreamFrame) error {
+	r, tty, err := app.containerLogsReader(ctx, params.ID, opts)
+	if err != nil {
+		return err
+	}
+	send := func(frame *types.StreamFrame) {
+		select {
+		case frames <- frame:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(frames)
+		defer r.Close()
+		err := demuxStream(r, tty, &frameWriter{id: params.ID, stream: "stdout", emit: send}, &frameWriter{id: params.ID, stream: "stderr", emit: send})
+		if err != nil && ctx.Err() == nil {
+			fmt.Println("[logs#follow]:", err.Error())
+		}
+	}()
+	return nil
+}
+func (app *App) containerLogsReader(ctx context.Context, id string, opts *types.ContainerLogsQuery) (io.ReadCloser, bool, error) {
+	cjson, err := app.client.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	showStdout, showStderr := opts.ShowStdout, opts.ShowStderr
+	if !showStdout && !showStderr {
+		showStdout, showStderr = true, true
+	}
+	r, err := app.client.ContainerLogs(ctx, id, container.LogsOptions{
+		ShowStdout: showStdout,
+		ShowStderr: showStderr,
+		Details:    opts.Details,
+		Timestamps: opts.Timestamps,
+		Tail:       opts.Tail,
+		Follow:     opts.Follow,
+		Since:      opts.Since,
+		Until:      opts.Until,
 	})
 	if err != nil {
-		return "", err
+		return nil, false, err
 	}
-	buf := bytes.NewBufferString("")
-	_, err = io.Copy(buf, r)
-	logs := buf.String()
-	return logs, err
+	return r, cjson.Config != nil && cjson.Config.Tty, nil
+}
+
+// demuxStream splits a docker multiplexed stream into stdout and stderr. Streams
+// of tty containers are not multiplexed and are copied to stdout as is.
+func demuxStream(r io.Reader, tty bool, stdout io.Writer, stderr io.Writer) error {
+	var err error
+	if tty {
+		_, err = io.Copy(stdout, r)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, r)
+	}
+	return err
 }
-func (app *App) ContainerTerminal(ctx *gin.Context) {}
 func (app *App) ContainerExec(params *types.ContainerExecParams, body *types.ContainerExecBody) error {
 	cmd := strings.Split(body.Cmd, " ")
 	env := strings.Split(body.Env, " ")
@@ -824,11 +1109,11 @@ func (app *App) ContainerExec(params *types.ContainerExecParams, body *types.Con
 			return err
 		}
 		fmt.Println("connection established:", params.ID)
-		attachedExec := app.AttachedExecs[params.ID]
+		attachedExec := app.getAttachedExec(params.ID)
 		if attachedExec != nil {
 			attachedExec.Close()
 		}
-		app.AttachedExecs[params.ID] = &hj
+		app.setAttachedExec(params.ID, &hj)
 
 		buf := bytes.Buffer{}
 		go func() {
@@ -846,7 +1131,7 @@ func (app *App) ContainerExec(params *types.ContainerExecParams, body *types.Con
 	return nil
 }
 func (app *App) ContainerExecCommand(params *types.ContainerExecCommandParams) error {
-	hj := app.AttachedExecs[params.ID]
+	hj := app.getAttachedExec(params.ID)
 	if hj == nil {
 		return dockertypes.ErrorResponse{
 			Message: "container is not connected",
@@ -855,43 +1140,11 @@ func (app *App) ContainerExecCommand(params *types.ContainerExecCommandParams) e
 	hj.Conn.Write([]byte(params.Cmd))
 	return nil
 }
-func (app *App) ContainersPrune() {
-	app.client.ContainersPrune(context.Background(), filters.NewArgs(filters.Arg("", "")))
-}
 
-func (app *App) ImagePull(params *types.ImagePullParams) (string, error) {
-	rc, err := app.client.ImagePull(context.Background(), params.Repo, image.PullOptions{})
-	if err != nil {
-		return "", err
-	}
-	buf := bytes.NewBufferString("")
-	io.Copy(buf, rc)
-	return buf.String(), nil
-}
 func (app *App) ImageCreate(ref string) error {
 	_, err := app.client.ImageCreate(context.Background(), ref, image.CreateOptions{})
 	return err
 }
-func (app *App) ImageBuild(src string, tags ...string) error {
-	fmt.Println("reading archive file...")
-	ff, err := os.Open(src)
-	if err != nil {
-		fmt.Println("error reading file: ", err)
-		return err
-	}
-	defer ff.Close()
-
-	fmt.Println("initiating build instance")
-	_, err = app.client.ImageBuild(context.Background(), ff, dockertypes.ImageBuildOptions{
-		Tags: tags,
-	})
-	if err != nil {
-		fmt.Println("error while building image: ", err.Error())
-		return err
-	}
-	fmt.Println("build completed successfully")
-	return nil
-}
 func (app *App) ImageInspect(id string) (*dockertypes.ImageInspect, error) {
 	i, _, err := app.client.ImageInspectWithRaw(context.Background(), id)
 	if err != nil {
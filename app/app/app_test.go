@@ -0,0 +1,63 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+func TestDemuxStream(t *testing.T) {
+	muxed := &bytes.Buffer{}
+	stdcopy.NewStdWriter(muxed, stdcopy.Stdout).Write([]byte("out 1\n"))
+	stdcopy.NewStdWriter(muxed, stdcopy.Stderr).Write([]byte("err 1\n"))
+	stdcopy.NewStdWriter(muxed, stdcopy.Stdout).Write([]byte("out 2\n"))
+
+	tests := []struct {
+		name           string
+		input          []byte
+		tty            bool
+		expectedStdout string
+		expectedStderr string
+	}{
+		{
+			name:           "multiplexed",
+			input:          muxed.Bytes(),
+			expectedStdout: "out 1\nout 2\n",
+			expectedStderr: "err 1\n",
+		},
+		{
+			name:           "tty",
+			input:          []byte("raw \x1b[1mtty\x1b[0m output\n"),
+			tty:            true,
+			expectedStdout: "raw \x1b[1mtty\x1b[0m output\n",
+		},
+		{
+			name:  "empty",
+			input: []byte{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			err := demuxStream(bytes.NewReader(tt.input), tt.tty, stdout, stderr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != tt.expectedStdout {
+				t.Errorf("stdout: got %q, want %q", stdout.String(), tt.expectedStdout)
+			}
+			if stderr.String() != tt.expectedStderr {
+				t.Errorf("stderr: got %q, want %q", stderr.String(), tt.expectedStderr)
+			}
+		})
+	}
+}
+
+func TestDemuxStreamCorrupt(t *testing.T) {
+	// a tty stream read as multiplexed has no valid frame headers
+	err := demuxStream(bytes.NewReader([]byte("plain text that is not framed")), false, &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil {
+		t.Error("expected an error for an unframed stream")
+	}
+}
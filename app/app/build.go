@@ -0,0 +1,298 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"reactor/types"
+	"reactor/utils"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+var buildStepPattern = regexp.MustCompile(`^Step (\d+)/(\d+) : `)
+
+// ImageBuild starts building an image from a tar build context as a background
+// job and returns the job right away. The decoded build output is broadcast as
+// "build_log" events on the /image namespace and the job result is the ID of
+// the built image. buildContext is closed when the build ends; cancelling the
+// job aborts the build on the daemon.
+func (app *App) ImageBuild(buildContext io.ReadCloser, params *types.ImageBuildParams) (*types.Job, error) {
+	opts, err := buildOptions(params)
+	if err != nil {
+		buildContext.Close()
+		return nil, err
+	}
+	ref := ""
+	if len(opts.Tags) > 0 {
+		ref = opts.Tags[0]
+	}
+	job := app.startJob("build", ref, "/image", func(ctx context.Context, id string) (string, error) {
+		defer buildContext.Close()
+		res, err := app.client.ImageBuild(ctx, buildContext, opts)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		return app.trackBuild(id, res.Body)
+	})
+	return job, nil
+}
+
+// buildOptions validates the build params and converts them into daemon
+// options. Build args and labels use the KEY=value form of the docker CLI.
+func buildOptions(params *types.ImageBuildParams) (dockertypes.ImageBuildOptions, error) {
+	opts := dockertypes.ImageBuildOptions{
+		Remove:     true,
+		Target:     params.Target,
+		NoCache:    params.NoCache,
+		PullParent: params.PullParent,
+		BuildArgs:  map[string]*string{},
+		Labels:     map[string]string{},
+		Tags:       make([]string, 0),
+	}
+	if params.Tag != "" {
+		opts.Tags = append(opts.Tags, params.Tag)
+	}
+	for i, tag := range params.Tags {
+		if strings.TrimSpace(tag) == "" || strings.ContainsAny(tag, " \t") {
+			return opts, fmt.Errorf("tags[%d]: %q is not a valid image reference", i, tag)
+		}
+		opts.Tags = append(opts.Tags, tag)
+	}
+	for i, arg := range params.BuildArgs {
+		key, value, found := strings.Cut(arg, "=")
+		if !found || key == "" {
+			return opts, fmt.Errorf("build_args[%d]: %q must be in KEY=value form", i, arg)
+		}
+		opts.BuildArgs[key] = &value
+	}
+	for i, label := range params.Labels {
+		key, value, found := strings.Cut(label, "=")
+		if !found || key == "" {
+			return opts, fmt.Errorf("labels[%d]: %q must be in key=value form", i, label)
+		}
+		opts.Labels[key] = value
+	}
+	if params.Dockerfile != "" {
+		dockerfile := path.Clean(params.Dockerfile)
+		if path.IsAbs(dockerfile) || dockerfile == ".." || strings.HasPrefix(dockerfile, "../") {
+			return opts, fmt.Errorf("dockerfile: %q must be a path inside the build context", params.Dockerfile)
+		}
+		opts.Dockerfile = dockerfile
+	}
+	if params.Platform != "" {
+		parts := strings.Split(params.Platform, "/")
+		for _, part := range parts {
+			if part == "" {
+				return opts, fmt.Errorf("platform: %q must be in os[/arch[/variant]] form", params.Platform)
+			}
+		}
+		if len(parts) > 3 {
+			return opts, fmt.Errorf("platform: %q must be in os[/arch[/variant]] form", params.Platform)
+		}
+		opts.Platform = params.Platform
+	}
+	return opts, nil
+}
+
+// trackBuild decodes the JSON message stream of a build, broadcasting each line
+// for job id, and returns the image ID reported in the aux message.
+func (app *App) trackBuild(id string, r io.Reader) (string, error) {
+	imageID := ""
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			return imageID, nil
+		}
+		if err != nil {
+			return imageID, err
+		}
+		event := &types.ImageBuildLog{
+			JobID:  id,
+			Stream: msg.Stream,
+		}
+		if msg.Error != nil {
+			event.Error = msg.Error.Message
+			app.emitJob("/image", "build_log", event)
+			return imageID, msg.Error
+		}
+		if msg.Aux != nil {
+			var aux struct {
+				ID string `json:"ID"`
+			}
+			if json.Unmarshal(*msg.Aux, &aux) == nil && aux.ID != "" {
+				imageID = aux.ID
+				event.ImageID = aux.ID
+			}
+		}
+		if match := buildStepPattern.FindStringSubmatch(msg.Stream); match != nil {
+			event.Step = match[0][len("Step ") : len(match[0])-len(" : ")]
+			step, _ := strconv.Atoi(match[1])
+			total, _ := strconv.Atoi(match[2])
+			if total > 0 {
+				app.setJobProgress(id, float64(step-1)/float64(total)*100)
+			}
+		}
+		if event.Stream == "" && event.ImageID == "" {
+			continue
+		}
+		app.emitJob("/image", "build_log", event)
+	}
+}
+
+// BuildContextFromFiles assembles a build context tar in memory from an inline
+// Dockerfile and uploaded files. Multipart uploads only carry the base name of
+// a file, so the path of each upload inside the context is taken from the
+// matching entry of params.Paths, falling back to the file name. Files
+// excluded by .dockerignore, either uploaded or given inline, are left out
+// except for the Dockerfile and .dockerignore themselves.
+func (app *App) BuildContextFromFiles(params *types.ImageBuildParams, files []*multipart.FileHeader) (io.ReadCloser, error) {
+	dockerfilePath := "Dockerfile"
+	if params.Dockerfile != "" {
+		dockerfilePath = path.Clean(params.Dockerfile)
+	}
+	if len(params.Paths) > 0 && len(params.Paths) != len(files) {
+		return nil, fmt.Errorf("paths: expected one path for each of the %d files, got %d", len(files), len(params.Paths))
+	}
+	entries := map[string]*multipart.FileHeader{}
+	for i, f := range files {
+		name := f.Filename
+		if len(params.Paths) > 0 {
+			name = params.Paths[i]
+		}
+		p, err := contextPath(name)
+		if err != nil {
+			return nil, fmt.Errorf("paths[%d]: %s", i, err.Error())
+		}
+		if entries[p] != nil {
+			return nil, fmt.Errorf("paths[%d]: %s is uploaded more than once", i, p)
+		}
+		entries[p] = f
+	}
+	if params.DockerfileContent == "" && entries[dockerfilePath] == nil {
+		return nil, fmt.Errorf("dockerfile_content: is required unless %s is uploaded", dockerfilePath)
+	}
+
+	ignore := params.Dockerignore
+	if ignore == "" && entries[".dockerignore"] != nil {
+		content, err := readFileHeader(entries[".dockerignore"])
+		if err != nil {
+			return nil, err
+		}
+		ignore = string(content)
+	}
+	matcher, err := utils.ParseDockerignore(strings.NewReader(ignore))
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(entries))
+	for p := range entries {
+		if p == dockerfilePath || p == ".dockerignore" {
+			paths = append(paths, p)
+			continue
+		}
+		ignored, err := matcher.MatchesOrParentMatches(p)
+		if err != nil {
+			return nil, fmt.Errorf("dockerignore: %s", err.Error())
+		}
+		if !ignored {
+			paths = append(paths, p)
+		}
+	}
+	if params.DockerfileContent != "" && entries[dockerfilePath] == nil {
+		paths = append(paths, dockerfilePath)
+	}
+	sort.Strings(paths)
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	now := time.Now()
+	dirs := map[string]bool{}
+	for _, p := range paths {
+		// parent directories get their own entries so the context extracts cleanly
+		for dir := path.Dir(p); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			dirs[dir] = true
+			err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     dir + "/",
+				Mode:     0755,
+				ModTime:  now,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		var content []byte
+		if p == dockerfilePath && params.DockerfileContent != "" {
+			content = []byte(params.DockerfileContent)
+		} else {
+			content, err = readFileHeader(entries[p])
+			if err != nil {
+				return nil, err
+			}
+		}
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     p,
+			Size:     int64(len(content)),
+			Mode:     0644,
+			ModTime:  now,
+		})
+		if err != nil {
+			return nil, err
+		}
+		_, err = tw.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		return nil, err
+	}
+	params.Dockerfile = dockerfilePath
+	return io.NopCloser(buf), nil
+}
+
+// contextPath validates the path of an uploaded file inside the build context.
+// It has to be relative and stay inside the context.
+func contextPath(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("path is empty")
+	}
+	if strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("%q is not a relative path", name)
+	}
+	if slices.Contains(strings.Split(name, "/"), "..") {
+		return "", fmt.Errorf("%q points outside the build context", name)
+	}
+	p := path.Clean(name)
+	if p == "." {
+		return "", fmt.Errorf("%q is not a file path", name)
+	}
+	return p, nil
+}
+func readFileHeader(f *multipart.FileHeader) ([]byte, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,39 @@
+package app
+
+import "testing"
+
+func TestContextPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		fails    bool
+	}{
+		{name: "Dockerfile", expected: "Dockerfile"},
+		{name: "src/main.go", expected: "src/main.go"},
+		{name: "./cmd//main.go", expected: "cmd/main.go"},
+		{name: "docker/Dockerfile", expected: "docker/Dockerfile"},
+		{name: "a..b/c", expected: "a..b/c"},
+		{name: "", fails: true},
+		{name: ".", fails: true},
+		{name: "/etc/passwd", fails: true},
+		{name: "../secret", fails: true},
+		{name: "src/../../secret", fails: true},
+		{name: "src/..", fails: true},
+	}
+	for _, tt := range tests {
+		p, err := contextPath(tt.name)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %q", tt.name, p)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tt.name, err.Error())
+			continue
+		}
+		if p != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.name, p, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"reactor/types"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/docker/go-units"
+)
+
+// buildHostConfig translates the host related fields of ContainerCreateParams
+// into the docker create configs. Errors name the offending field so they can
+// be shown next to it in the create form.
+func buildHostConfig(params *types.ContainerCreateParams) (nat.PortSet, *container.HostConfig, *network.NetworkingConfig, error) {
+	hostConfig := &container.HostConfig{
+		AutoRemove:      params.Autoremove,
+		PublishAllPorts: params.ExposeAllPorts,
+		Privileged:      params.Privileged,
+		CapAdd:          params.CapAdd,
+		CapDrop:         params.CapDrop,
+		DNSSearch:       params.DnsSearch,
+	}
+
+	exposedPorts, portBindings, err := parsePortBindings(params.Ports)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hostConfig.PortBindings = portBindings
+
+	for i, m := range params.Mounts {
+		mnt, err := parseMount(&m)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("mounts[%d].%s", i, err.Error())
+		}
+		hostConfig.Mounts = append(hostConfig.Mounts, *mnt)
+	}
+
+	restartPolicy, err := parseRestartPolicy(params.RestartPolicy)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("restart_policy: %s", err.Error())
+	}
+	if params.Autoremove && !restartPolicy.IsNone() {
+		return nil, nil, nil, fmt.Errorf("auto_remove: cannot be combined with restart_policy %q", params.RestartPolicy)
+	}
+	hostConfig.RestartPolicy = restartPolicy
+
+	if params.Memory != "" {
+		hostConfig.Memory, err = units.RAMInBytes(params.Memory)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("memory: %s", err.Error())
+		}
+	}
+	if params.MemorySwap != "" {
+		if params.MemorySwap == "-1" {
+			hostConfig.MemorySwap = -1
+		} else {
+			hostConfig.MemorySwap, err = units.RAMInBytes(params.MemorySwap)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("memory_swap: %s", err.Error())
+			}
+		}
+		if hostConfig.Memory == 0 {
+			return nil, nil, nil, fmt.Errorf("memory_swap: requires memory to be set")
+		}
+		if hostConfig.MemorySwap > 0 && hostConfig.MemorySwap < hostConfig.Memory {
+			return nil, nil, nil, fmt.Errorf("memory_swap: must be larger than memory")
+		}
+	}
+	if params.Cpus < 0 {
+		return nil, nil, nil, fmt.Errorf("cpus: must not be negative")
+	}
+	hostConfig.NanoCPUs = int64(params.Cpus * 1e9)
+	if params.CpuShares < 0 {
+		return nil, nil, nil, fmt.Errorf("cpu_shares: must not be negative")
+	}
+	hostConfig.CPUShares = params.CpuShares
+
+	for i, host := range params.ExtraHosts {
+		name, ip, found := strings.Cut(host, ":")
+		if !found || name == "" || (ip != "host-gateway" && net.ParseIP(ip) == nil) {
+			return nil, nil, nil, fmt.Errorf("extra_hosts[%d]: %q is not in host:ip form", i, host)
+		}
+	}
+	hostConfig.ExtraHosts = params.ExtraHosts
+	for i, dns := range params.Dns {
+		if net.ParseIP(dns) == nil {
+			return nil, nil, nil, fmt.Errorf("dns[%d]: %q is not an IP address", i, dns)
+		}
+	}
+	hostConfig.DNS = params.Dns
+
+	var networkingConfig *network.NetworkingConfig
+	if params.Network != "" {
+		hostConfig.NetworkMode = container.NetworkMode(params.Network)
+		if len(params.NetworkAliases) > 0 {
+			if !hostConfig.NetworkMode.IsUserDefined() {
+				return nil, nil, nil, fmt.Errorf("network_aliases: only supported on user defined networks")
+			}
+			networkingConfig = &network.NetworkingConfig{
+				EndpointsConfig: map[string]*network.EndpointSettings{
+					params.Network: {Aliases: params.NetworkAliases},
+				},
+			}
+		}
+	} else if len(params.NetworkAliases) > 0 {
+		return nil, nil, nil, fmt.Errorf("network_aliases: requires network to be set")
+	}
+
+	return exposedPorts, hostConfig, networkingConfig, nil
+}
+
+func parsePortBindings(ports []types.ContainerPortBinding) (nat.PortSet, nat.PortMap, error) {
+	exposed := nat.PortSet{}
+	bindings := nat.PortMap{}
+	for i, p := range ports {
+		if p.ContainerPort == "" {
+			return nil, nil, fmt.Errorf("ports[%d].container_port: is required", i)
+		}
+		proto, containerPort := nat.SplitProtoPort(p.ContainerPort)
+		if proto != "tcp" && proto != "udp" && proto != "sctp" {
+			return nil, nil, fmt.Errorf("ports[%d].container_port: unknown protocol %q", i, proto)
+		}
+		port, err := nat.NewPort(proto, containerPort)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ports[%d].container_port: %s", i, err.Error())
+		}
+		if p.HostPort != "" {
+			_, _, err = nat.ParsePortRangeToInt(p.HostPort)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ports[%d].host_port: %s", i, err.Error())
+			}
+		}
+		if p.HostIP != "" && net.ParseIP(p.HostIP) == nil {
+			return nil, nil, fmt.Errorf("ports[%d].host_ip: %q is not an IP address", i, p.HostIP)
+		}
+		exposed[port] = struct{}{}
+		bindings[port] = append(bindings[port], nat.PortBinding{
+			HostIP:   p.HostIP,
+			HostPort: p.HostPort,
+		})
+	}
+	return exposed, bindings, nil
+}
+
+func parseMount(m *types.ContainerMount) (*mount.Mount, error) {
+	if m.Target == "" || !path.IsAbs(m.Target) {
+		return nil, fmt.Errorf("target: must be an absolute path")
+	}
+	mountType := m.Type
+	if mountType == "" {
+		// same heuristic as `docker run -v`: paths are binds, names are volumes
+		mountType = string(mount.TypeVolume)
+		if path.IsAbs(m.Source) {
+			mountType = string(mount.TypeBind)
+		}
+	}
+	switch mount.Type(mountType) {
+	case mount.TypeBind:
+		if !path.IsAbs(m.Source) {
+			return nil, fmt.Errorf("source: bind mounts need an absolute host path")
+		}
+	case mount.TypeVolume:
+	case mount.TypeTmpfs:
+		if m.Source != "" {
+			return nil, fmt.Errorf("source: must be empty for tmpfs mounts")
+		}
+	default:
+		return nil, fmt.Errorf("type: must be one of bind, volume or tmpfs")
+	}
+	return &mount.Mount{
+		Type:     mount.Type(mountType),
+		Source:   m.Source,
+		Target:   m.Target,
+		ReadOnly: m.ReadOnly,
+	}, nil
+}
+
+// parseRestartPolicy accepts the `docker run --restart` syntax, e.g. "always"
+// or "on-failure:3".
+func parseRestartPolicy(policy string) (container.RestartPolicy, error) {
+	rp := container.RestartPolicy{}
+	if policy == "" {
+		return rp, nil
+	}
+	name, retries, found := strings.Cut(policy, ":")
+	rp.Name = container.RestartPolicyMode(name)
+	if found {
+		count, err := strconv.Atoi(retries)
+		if err != nil {
+			return rp, fmt.Errorf("maximum retry count must be a number")
+		}
+		rp.MaximumRetryCount = count
+	}
+	return rp, container.ValidateRestartPolicy(rp)
+}
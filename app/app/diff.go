@@ -0,0 +1,169 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"reactor/types"
+	"slices"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ImageDiff compares the filesystems the layers of two images add up to,
+// listing paths that B adds, removes or changes relative to A, along with the
+// differences in their runtime config. Files are compared by content, so a
+// rebuilt but identical file is not reported.
+func (app *App) ImageDiff(query *types.ImageDiffQuery) (*types.ImageDiff, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = layerFilesLimit
+	}
+	if limit > layerFilesMaxLimit {
+		return nil, fmt.Errorf("limit: cannot exceed %d", layerFilesMaxLimit)
+	}
+	a, err := app.ImageInspect(query.A)
+	if err != nil {
+		return nil, fmt.Errorf("a: %s", err.Error())
+	}
+	b, err := app.ImageInspect(query.B)
+	if err != nil {
+		return nil, fmt.Errorf("b: %s", err.Error())
+	}
+	fa, err := app.analyzeImage(a.ID)
+	if err != nil {
+		return nil, fmt.Errorf("a: %s", err.Error())
+	}
+	fb, err := app.analyzeImage(b.ID)
+	if err != nil {
+		return nil, fmt.Errorf("b: %s", err.Error())
+	}
+
+	prefix := ""
+	if query.Path != "" && path.Clean("/"+query.Path) != "/" {
+		prefix = path.Clean("/" + query.Path)
+	}
+	diff := &types.ImageDiff{
+		A:      a.ID,
+		B:      b.ID,
+		Paths:  make([]types.ImagePathDiff, 0),
+		Config: diffImageConfig(a.Config, b.Config),
+	}
+	paths := slices.Collect(maps.Keys(fa.files))
+	for p := range fb.files {
+		if _, ok := fa.files[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	slices.Sort(paths)
+	for _, p := range paths {
+		if prefix != "" && p != prefix && !strings.HasPrefix(p, prefix+"/") {
+			continue
+		}
+		before, inA := fa.files[p]
+		after, inB := fb.files[p]
+		d := types.ImagePathDiff{Path: p}
+		switch {
+		case !inA:
+			d.Change = "added"
+			diff.Added++
+		case !inB:
+			d.Change = "removed"
+			diff.Removed++
+		case before.mode != after.mode || before.digest != after.digest:
+			d.Change = "changed"
+			diff.Changed++
+		default:
+			continue
+		}
+		if inA {
+			d.Type = fileType(before.mode)
+			d.SizeA = before.size
+			d.ModeA = before.mode.String()
+		}
+		if inB {
+			d.Type = fileType(after.mode)
+			d.SizeB = after.size
+			d.ModeB = after.mode.String()
+		}
+		d.SizeDelta = d.SizeB - d.SizeA
+		diff.SizeDelta += d.SizeDelta
+		diff.Total++
+		if len(diff.Paths) < limit {
+			diff.Paths = append(diff.Paths, d)
+		}
+	}
+	diff.Truncated = diff.Total > len(diff.Paths)
+	return diff, nil
+}
+
+func diffImageConfig(a *container.Config, b *container.Config) types.ImageConfigDiff {
+	if a == nil {
+		a = &container.Config{}
+	}
+	if b == nil {
+		b = &container.Config{}
+	}
+	env := func(c *container.Config) map[string]string {
+		m := make(map[string]string, len(c.Env))
+		for _, e := range c.Env {
+			k, v, _ := strings.Cut(e, "=")
+			m[k] = v
+		}
+		return m
+	}
+	ports := func(c *container.Config) map[string]string {
+		m := make(map[string]string, len(c.ExposedPorts))
+		for p := range c.ExposedPorts {
+			m[string(p)] = ""
+		}
+		return m
+	}
+	exposed := diffKeyed(ports(a), ports(b))
+	return types.ImageConfigDiff{
+		Env:        diffKeyed(env(a), env(b)),
+		Cmd:        diffList(a.Cmd, b.Cmd),
+		Entrypoint: diffList(a.Entrypoint, b.Entrypoint),
+		ExposedPorts: types.ImageSetDiff{
+			Added:   slices.Sorted(maps.Keys(exposed.Added)),
+			Removed: slices.Sorted(maps.Keys(exposed.Removed)),
+		},
+		Labels: diffKeyed(a.Labels, b.Labels),
+	}
+}
+
+func diffKeyed(a map[string]string, b map[string]string) types.ImageKeyedDiff {
+	diff := types.ImageKeyedDiff{
+		Added:   map[string]string{},
+		Removed: map[string]string{},
+		Changed: make([]types.ImageValueChange, 0),
+	}
+	for k, v := range a {
+		after, ok := b[k]
+		if !ok {
+			diff.Removed[k] = v
+		} else if after != v {
+			diff.Changed = append(diff.Changed, types.ImageValueChange{Key: k, A: v, B: after})
+		}
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			diff.Added[k] = v
+		}
+	}
+	slices.SortFunc(diff.Changed, func(x, y types.ImageValueChange) int {
+		return strings.Compare(x.Key, y.Key)
+	})
+	return diff
+}
+
+func diffList(a []string, b []string) types.ImageListDiff {
+	if a == nil {
+		a = make([]string, 0)
+	}
+	if b == nil {
+		b = make([]string, 0)
+	}
+	return types.ImageListDiff{A: a, B: b, Changed: !slices.Equal(a, b)}
+}
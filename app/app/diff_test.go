@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"reactor/types"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+)
+
+func TestDiffKeyed(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       map[string]string
+		b       map[string]string
+		added   map[string]string
+		removed map[string]string
+		changed []types.ImageValueChange
+	}{
+		{name: "empty"},
+		{
+			name:  "equal",
+			a:     map[string]string{"PATH": "/bin", "EMPTY": ""},
+			b:     map[string]string{"PATH": "/bin", "EMPTY": ""},
+			added: map[string]string{}, removed: map[string]string{},
+		},
+		{
+			name:    "added and removed",
+			a:       map[string]string{"OLD": "1", "KEEP": "x"},
+			b:       map[string]string{"NEW": "2", "KEEP": "x"},
+			added:   map[string]string{"NEW": "2"},
+			removed: map[string]string{"OLD": "1"},
+		},
+		{
+			name:    "changed, sorted by key",
+			a:       map[string]string{"Z": "1", "A": "1", "E": "set"},
+			b:       map[string]string{"Z": "2", "A": "2", "E": ""},
+			changed: []types.ImageValueChange{{Key: "A", A: "1", B: "2"}, {Key: "E", A: "set", B: ""}, {Key: "Z", A: "1", B: "2"}},
+		},
+		{
+			name:    "from nothing",
+			b:       map[string]string{"A": "1"},
+			added:   map[string]string{"A": "1"},
+			removed: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		diff := diffKeyed(tt.a, tt.b)
+		if fmt.Sprint(diff.Added) != fmt.Sprint(orEmpty(tt.added)) || fmt.Sprint(diff.Removed) != fmt.Sprint(orEmpty(tt.removed)) {
+			t.Errorf("%s: added %v removed %v, want %v %v", tt.name, diff.Added, diff.Removed, tt.added, tt.removed)
+		}
+		if diff.Added == nil || diff.Removed == nil || diff.Changed == nil {
+			t.Errorf("%s: maps and slices should never be nil", tt.name)
+		}
+		if fmt.Sprint(diff.Changed) != fmt.Sprint(tt.changed) {
+			t.Errorf("%s: changed %v, want %v", tt.name, diff.Changed, tt.changed)
+		}
+	}
+}
+
+func orEmpty(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func TestDiffImageConfig(t *testing.T) {
+	a := &container.Config{
+		Env:          []string{"PATH=/bin", "DEBUG=1"},
+		Cmd:          []string{"serve"},
+		ExposedPorts: nat.PortSet{"80/tcp": {}, "443/tcp": {}},
+	}
+	b := &container.Config{
+		Env:          []string{"PATH=/usr/bin", "MODE=prod"},
+		Cmd:          []string{"serve"},
+		Entrypoint:   []string{"/init"},
+		ExposedPorts: nat.PortSet{"443/tcp": {}, "8080/tcp": {}},
+	}
+	diff := diffImageConfig(a, b)
+	if fmt.Sprint(diff.Env.Added) != "map[MODE:prod]" || fmt.Sprint(diff.Env.Removed) != "map[DEBUG:1]" || len(diff.Env.Changed) != 1 {
+		t.Errorf("unexpected env diff %+v", diff.Env)
+	}
+	if diff.Cmd.Changed || !diff.Entrypoint.Changed || diff.Entrypoint.A == nil {
+		t.Errorf("unexpected cmd %+v or entrypoint %+v", diff.Cmd, diff.Entrypoint)
+	}
+	if fmt.Sprint(diff.ExposedPorts.Added) != "[8080/tcp]" || fmt.Sprint(diff.ExposedPorts.Removed) != "[80/tcp]" {
+		t.Errorf("unexpected ports %+v", diff.ExposedPorts)
+	}
+
+	// images without a config compare as empty
+	diff = diffImageConfig(nil, nil)
+	if diff.Cmd.Changed || len(diff.Env.Added) != 0 {
+		t.Errorf("unexpected diff of empty configs %+v", diff)
+	}
+}
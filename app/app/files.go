@@ -0,0 +1,244 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reactor/types"
+	"reactor/utils"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	defaultPreviewLimit int64 = 64 << 10
+	maxPreviewLimit     int64 = 1 << 20
+	// the archive API sends the whole subtree, so a listing stops reading it
+	// after this many entries or bytes and reports itself as truncated
+	maxListScanEntries       = 10000
+	maxListScanBytes   int64 = 256 << 20
+)
+
+// ContainerListFiles lists the entries directly under query.Path. It reads the
+// archive API rather than running ls, so it also works on stopped containers
+// and images without a shell. The daemon sends the whole subtree, so path is
+// required rather than defaulting to the root, and listings of large
+// directories close to the root are cut short and marked as truncated.
+func (app *App) ContainerListFiles(params *types.ContainerRequestParams, query *types.ContainerFilesQuery) (*types.FileListing, error) {
+	if query.Path == "" {
+		return nil, fmt.Errorf("path: is required")
+	}
+	return app.listFiles(params.ID, query.Path, query.Path)
+}
+
+// listFiles lists the entries under src inside container id, reporting paths
+// relative to dir.
+func (app *App) listFiles(id string, src string, dir string) (*types.FileListing, error) {
+	stat, err := app.client.ContainerStatPath(context.Background(), id, src)
+	if err != nil {
+		return nil, err
+	}
+	if stat.Mode&os.ModeSymlink != 0 {
+		// follow the link so that linked directories can be browsed
+		src = strings.TrimSuffix(src, "/") + "/."
+	}
+	rc, _, err := app.client.CopyFromContainer(context.Background(), id, src)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	root, entries, truncated, err := listArchiveDir(rc, dir)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil {
+		root = &types.FileEntry{
+			Name:       stat.Name,
+			Path:       dir,
+			Type:       fileType(stat.Mode),
+			Size:       stat.Size,
+			Mode:       stat.Mode.String(),
+			Mtime:      stat.Mtime,
+			LinkTarget: stat.LinkTarget,
+		}
+	}
+	return &types.FileListing{
+		Path:      dir,
+		Entry:     root,
+		Entries:   entries,
+		Truncated: truncated,
+	}, nil
+}
+
+// ContainerPreviewFile returns up to query.Limit bytes of a regular file,
+// following symlinks. Text is returned as is, anything else base64 encoded.
+func (app *App) ContainerPreviewFile(params *types.ContainerRequestParams, query *types.ContainerFilesQuery) (*types.FilePreview, error) {
+	if query.Path == "" {
+		return nil, fmt.Errorf("path: is required")
+	}
+	return app.previewFile(params.ID, "/", query.Path, query.Path, query.Limit)
+}
+
+// previewFile previews the file at src inside container id, reporting it as p.
+// A symlink is followed as long as it resolves to somewhere under root, which
+// is only narrower than "/" for volumes mounted in a helper container.
+func (app *App) previewFile(id string, root string, src string, p string, limit int64) (*types.FilePreview, error) {
+	stat, err := app.client.ContainerStatPath(context.Background(), id, src)
+	if err != nil {
+		return nil, err
+	}
+	if stat.Mode&os.ModeSymlink != 0 {
+		// the daemon reports the fully resolved, absolute target
+		target := stat.LinkTarget
+		if root != "/" && target != root && !strings.HasPrefix(target, root+"/") {
+			return nil, fmt.Errorf("path: %s links outside the volume", p)
+		}
+		src = target
+	}
+	rc, stat, err := app.client.CopyFromContainer(context.Background(), id, src)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	if !stat.Mode.IsRegular() {
+		return nil, fmt.Errorf("path: %s is not a regular file", p)
+	}
+	return previewArchiveFile(rc, p, limit)
+}
+
+// listArchiveDir reads a tar stream as produced by the archive API and returns
+// the root entry and its direct children, sorted with directories first. It
+// gives up after maxListScanEntries entries or maxListScanBytes bytes, in
+// which case the children found so far are returned and truncated is set.
+func listArchiveDir(r io.Reader, dir string) (*types.FileEntry, []*types.FileEntry, bool, error) {
+	lr := &io.LimitedReader{R: r, N: maxListScanBytes}
+	tr := tar.NewReader(lr)
+	var root *types.FileEntry
+	prefix := ""
+	entries := make([]*types.FileEntry, 0)
+	truncated := false
+	for scanned := 0; ; scanned++ {
+		if scanned == maxListScanEntries {
+			truncated = true
+			break
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF && lr.N > 0 {
+			break
+		}
+		if err != nil {
+			if lr.N <= 0 && root != nil {
+				truncated = true
+				break
+			}
+			return nil, nil, false, err
+		}
+		name := strings.TrimSuffix(hdr.Name, "/")
+		if root == nil {
+			if name != "" {
+				prefix = name + "/"
+			}
+			root = archiveEntry(hdr, path.Base(dir), dir)
+			if hdr.Typeflag != tar.TypeDir {
+				// a single file or link, there is nothing below it
+				break
+			}
+			continue
+		}
+		rel, found := strings.CutPrefix(name, prefix)
+		if !found || rel == "" || strings.Contains(rel, "/") {
+			continue
+		}
+		entries = append(entries, archiveEntry(hdr, rel, path.Join(dir, rel)))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		di, dj := entries[i].Type == "dir", entries[j].Type == "dir"
+		if di != dj {
+			return di
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return root, entries, truncated, nil
+}
+
+// previewArchiveFile reads the first regular file of a tar stream, keeping at
+// most limit bytes of its content.
+func previewArchiveFile(r io.Reader, p string, limit int64) (*types.FilePreview, error) {
+	if limit <= 0 {
+		limit = defaultPreviewLimit
+	}
+	if limit > maxPreviewLimit {
+		limit = maxPreviewLimit
+	}
+	tr := tar.NewReader(r)
+	hdr, err := utils.FirstFileInArchive(tr)
+	if err != nil {
+		return nil, err
+	}
+	buf := bytes.Buffer{}
+	_, err = io.CopyN(&buf, tr, limit)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	content := buf.Bytes()
+	preview := &types.FilePreview{
+		Path:      p,
+		Name:      path.Base(hdr.Name),
+		Size:      hdr.Size,
+		Truncated: hdr.Size > int64(len(content)),
+	}
+	if isText(content) {
+		preview.Encoding = "utf-8"
+		preview.Content = string(content)
+	} else {
+		preview.Encoding = "base64"
+		preview.Content = base64.StdEncoding.EncodeToString(content)
+	}
+	return preview, nil
+}
+
+func archiveEntry(hdr *tar.Header, name string, p string) *types.FileEntry {
+	info := hdr.FileInfo()
+	return &types.FileEntry{
+		Name:       name,
+		Path:       p,
+		Type:       fileType(info.Mode()),
+		Size:       hdr.Size,
+		Mode:       info.Mode().String(),
+		Mtime:      hdr.ModTime,
+		LinkTarget: hdr.Linkname,
+	}
+}
+func fileType(mode os.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "dir"
+	case mode&os.ModeSymlink != 0:
+		return "symlink"
+	case mode.IsRegular():
+		return "file"
+	default:
+		return "other"
+	}
+}
+
+// isText reports whether content looks like text. A multi-byte character cut
+// off by the preview limit does not make it binary.
+func isText(content []byte) bool {
+	if bytes.IndexByte(content, 0) >= 0 {
+		return false
+	}
+	for i := 0; i < utf8.UTFMax && len(content) > 0; i++ {
+		if utf8.Valid(content) {
+			return true
+		}
+		content = content[:len(content)-1]
+	}
+	return utf8.Valid(content)
+}
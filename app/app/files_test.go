@@ -0,0 +1,97 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// tarOf builds an archive of empty entries, names ending in / are directories
+func tarOf(t *testing.T, names ...string) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644}
+		if name[len(name)-1] == '/' {
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestListArchiveDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		archive  []string
+		dir      string
+		expected []string
+	}{
+		{
+			name:     "direct children only, directories first",
+			archive:  []string{"etc/", "etc/passwd", "etc/ssl/", "etc/ssl/certs/", "etc/ssl/certs/ca.pem", "etc/hosts"},
+			dir:      "/etc",
+			expected: []string{"/etc/ssl", "/etc/hosts", "/etc/passwd"},
+		},
+		{
+			name:     "root",
+			archive:  []string{"/", "bin/", "bin/sh", "tmp/"},
+			dir:      "/",
+			expected: []string{"/bin", "/tmp"},
+		},
+		{
+			name:     "single file",
+			archive:  []string{"hosts", "trailing"},
+			dir:      "/etc/hosts",
+			expected: []string{},
+		},
+		{
+			name:     "empty directory",
+			archive:  []string{"tmp/"},
+			dir:      "/tmp",
+			expected: []string{},
+		},
+	}
+	for _, tt := range tests {
+		root, entries, truncated, err := listArchiveDir(tarOf(t, tt.archive...), tt.dir)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err.Error())
+			continue
+		}
+		if root == nil || root.Path != tt.dir || truncated {
+			t.Errorf("%s: unexpected root %+v, truncated %v", tt.name, root, truncated)
+		}
+		paths := make([]string, 0, len(entries))
+		for _, e := range entries {
+			paths = append(paths, e.Path)
+		}
+		if fmt.Sprint(paths) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: got %v, want %v", tt.name, paths, tt.expected)
+		}
+	}
+}
+
+func TestListArchiveDirTruncated(t *testing.T) {
+	names := []string{"/", "a/", "b"}
+	for i := 0; i < maxListScanEntries; i++ {
+		names = append(names, fmt.Sprintf("a/%d", i))
+	}
+	names = append(names, "z")
+	root, entries, truncated, err := listArchiveDir(tarOf(t, names...), "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root == nil || !truncated {
+		t.Fatalf("expected a truncated listing, got root %+v, truncated %v", root, truncated)
+	}
+	if len(entries) != 2 || entries[0].Name != "a" || entries[1].Name != "b" {
+		t.Errorf("unexpected entries %v", entries)
+	}
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"reactor/types"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// largestLayers is how many of the biggest steps are called out on their own
+const largestLayers = 5
+
+// build args prefix RUN steps as "|2 A=1 B=2 /bin/sh -c ..."
+var buildArgsPrefix = regexp.MustCompile(`^\|\d+ (?:\S+=\S* )*`)
+
+// ImageHistory returns the image's layers oldest first with the Dockerfile
+// step that created each, along with per-instruction size totals and the
+// largest steps so bloated ones stand out.
+func (app *App) ImageHistory(id string) (*types.ImageHistory, error) {
+	items, err := app.client.ImageHistory(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	history := &types.ImageHistory{
+		ID:           id,
+		Layers:       make([]types.ImageLayer, 0, len(items)),
+		Largest:      make([]types.ImageLayer, 0, largestLayers),
+		Instructions: make([]types.ImageInstructionSize, 0),
+	}
+	for _, item := range items {
+		history.Size += item.Size
+	}
+	totals := make(map[string]*types.ImageInstructionSize)
+	// the daemon lists the newest layer first
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		instruction, command := parseCreatedBy(item.CreatedBy)
+		cumulative := item.Size
+		if n := len(history.Layers); n > 0 {
+			cumulative += history.Layers[n-1].Cumulative
+		}
+		tags := item.Tags
+		if tags == nil {
+			tags = make([]string, 0)
+		}
+		history.Layers = append(history.Layers, types.ImageLayer{
+			ID:          item.ID,
+			Created:     time.Unix(item.Created, 0),
+			CreatedBy:   item.CreatedBy,
+			Instruction: instruction,
+			Command:     command,
+			Comment:     item.Comment,
+			Tags:        tags,
+			Size:        item.Size,
+			Cumulative:  cumulative,
+			Percent:     percentOf(item.Size, history.Size),
+			Empty:       item.Size == 0,
+		})
+		total, ok := totals[instruction]
+		if !ok {
+			total = &types.ImageInstructionSize{Instruction: instruction}
+			totals[instruction] = total
+		}
+		total.Layers++
+		total.Size += item.Size
+	}
+	for _, total := range totals {
+		total.Percent = percentOf(total.Size, history.Size)
+		history.Instructions = append(history.Instructions, *total)
+	}
+	slices.SortFunc(history.Instructions, func(a, b types.ImageInstructionSize) int {
+		if a.Size != b.Size {
+			return cmp.Compare(b.Size, a.Size)
+		}
+		return strings.Compare(a.Instruction, b.Instruction)
+	})
+	for _, layer := range history.Layers {
+		if !layer.Empty {
+			history.Largest = append(history.Largest, layer)
+		}
+	}
+	slices.SortStableFunc(history.Largest, func(a, b types.ImageLayer) int {
+		return cmp.Compare(b.Size, a.Size)
+	})
+	if len(history.Largest) > largestLayers {
+		history.Largest = history.Largest[:largestLayers]
+	}
+	return history, nil
+}
+
+// parseCreatedBy splits a history entry into its Dockerfile instruction and
+// the rest of the step. The classic builder records steps as shell commands,
+// with "#(nop)" marking metadata-only instructions, while BuildKit records
+// the instruction itself. Entries without one, such as commits and imports,
+// are reported as "".
+func parseCreatedBy(createdBy string) (string, string) {
+	s := strings.TrimSpace(createdBy)
+	s = strings.TrimSuffix(s, "# buildkit")
+	s = buildArgsPrefix.ReplaceAllString(s, "")
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ""
+	}
+	if rest, found := strings.CutPrefix(s, "/bin/sh -c "); found {
+		rest = strings.TrimSpace(rest)
+		if nop, found := strings.CutPrefix(rest, "#(nop)"); found {
+			s = strings.TrimSpace(nop)
+		} else {
+			return "RUN", rest
+		}
+	}
+	instruction, command, _ := strings.Cut(s, " ")
+	if instruction != strings.ToUpper(instruction) {
+		// a bare command, e.g. from an image committed with a custom CMD
+		return "", s
+	}
+	command = strings.TrimSpace(command)
+	if instruction == "RUN" {
+		command = buildArgsPrefix.ReplaceAllString(command, "")
+		command = strings.TrimPrefix(command, "/bin/sh -c ")
+	}
+	return instruction, strings.TrimSpace(command)
+}
+
+func percentOf(size int64, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(size) / float64(total) * 100
+}
@@ -0,0 +1,42 @@
+package app
+
+import "testing"
+
+func TestParseCreatedBy(t *testing.T) {
+	tests := []struct {
+		createdBy   string
+		instruction string
+		command     string
+	}{
+		// classic builder
+		{createdBy: "/bin/sh -c apt-get update", instruction: "RUN", command: "apt-get update"},
+		{createdBy: `/bin/sh -c #(nop)  CMD ["nginx" "-g" "daemon off;"]`, instruction: "CMD", command: `["nginx" "-g" "daemon off;"]`},
+		{createdBy: "/bin/sh -c #(nop) ADD file:abc123 in / ", instruction: "ADD", command: "file:abc123 in /"},
+		{createdBy: "|2 VERSION=1.2 EMPTY= /bin/sh -c make install", instruction: "RUN", command: "make install"},
+		// buildkit
+		{createdBy: "RUN /bin/sh -c go build ./... # buildkit", instruction: "RUN", command: "go build ./..."},
+		{createdBy: "RUN |1 TARGET=prod /bin/sh -c npm ci # buildkit", instruction: "RUN", command: "npm ci"},
+		{createdBy: "COPY . /src # buildkit", instruction: "COPY", command: ". /src"},
+		{createdBy: "WORKDIR /src", instruction: "WORKDIR", command: "/src"},
+		{createdBy: "ENTRYPOINT [\"/app\"]", instruction: "ENTRYPOINT", command: `["/app"]`},
+		// no instruction
+		{createdBy: "", instruction: "", command: ""},
+		{createdBy: "  # buildkit", instruction: "", command: ""},
+		{createdBy: "bash -c 'echo hi'", instruction: "", command: "bash -c 'echo hi'"},
+	}
+	for _, tt := range tests {
+		instruction, command := parseCreatedBy(tt.createdBy)
+		if instruction != tt.instruction || command != tt.command {
+			t.Errorf("%q: got %q %q, want %q %q", tt.createdBy, instruction, command, tt.instruction, tt.command)
+		}
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	if p := percentOf(25, 200); p != 12.5 {
+		t.Errorf("got %v, want 12.5", p)
+	}
+	if p := percentOf(25, 0); p != 0 {
+		t.Errorf("got %v for an empty total", p)
+	}
+}
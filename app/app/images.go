@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reactor/types"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+)
+
+// ImageTag adds repo[:tag] as a new reference to the image and returns the
+// reference in its familiar form.
+func (app *App) ImageTag(id string, params *types.ImageTagParams) (string, error) {
+	ref, err := imageRef(params.Repo, params.Tag)
+	if err != nil {
+		return "", err
+	}
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("repo: %s", err.Error())
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return "", fmt.Errorf("repo: cannot tag with a digest reference")
+	}
+	target := reference.FamiliarString(reference.TagNameOnly(named))
+	err = app.client.ImageTag(context.Background(), id, target)
+	if err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// ImageUntag removes a single tag. Removing the last tag of an image would
+// delete the image as well, so that is refused in favour of ImageRemove.
+func (app *App) ImageUntag(params *types.ImageUntagParams) (*types.PruneReport, error) {
+	named, err := reference.ParseNormalizedNamed(params.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("ref: %s", err.Error())
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return nil, fmt.Errorf("ref: %q is not a tag", params.Ref)
+	}
+	ref := reference.FamiliarString(reference.TagNameOnly(named))
+	i, _, err := app.client.ImageInspectWithRaw(context.Background(), ref)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(i.RepoTags, ref) {
+		return nil, fmt.Errorf("ref: %q is not a tag", params.Ref)
+	}
+	if len(i.RepoTags) == 1 {
+		return nil, fmt.Errorf("ref: %q is the only tag of image %s, remove the image instead", ref, i.ID)
+	}
+	resp, err := app.client.ImageRemove(context.Background(), ref, image.RemoveOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return imageDeleteReport(resp, nil), nil
+}
+
+// ImageRemove removes the image and, unless noprune is set, its untagged
+// parents. The reclaimed space is each deleted image's size minus what it
+// shares with images that remain.
+func (app *App) ImageRemove(id string, query *types.ImageRemoveQuery) (*types.PruneReport, error) {
+	sizes, err := app.imageUniqueSizes()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := app.client.ImageRemove(context.Background(), id, image.RemoveOptions{
+		Force:         query.Force,
+		PruneChildren: !query.NoPrune,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return imageDeleteReport(resp, sizes), nil
+}
+
+// ImagesPrune removes unused images matching the filters in params. Only
+// dangling images are considered unless dangling is explicitly false, in
+// which case every image without a container is. Dry runs mirror that
+// selection without removing anything.
+func (app *App) ImagesPrune(params *types.ImagePruneParams) (*types.PruneReport, error) {
+	args, until, err := pruneFilters(&params.PruneParams)
+	if err != nil {
+		return nil, err
+	}
+	dangling := params.Dangling == nil || *params.Dangling
+	args.Add("dangling", strconv.FormatBool(dangling))
+	if !params.DryRun {
+		report, err := app.client.ImagesPrune(context.Background(), args)
+		if err != nil {
+			return nil, err
+		}
+		r := imageDeleteReport(report.ImagesDeleted, nil)
+		r.SpaceReclaimed = report.SpaceReclaimed
+		return r, nil
+	}
+
+	images, err := app.client.ImageList(context.Background(), image.ListOptions{SharedSize: true})
+	if err != nil {
+		return nil, err
+	}
+	containers, err := app.client.ContainerList(context.Background(), container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool)
+	for _, c := range containers {
+		used[c.ImageID] = true
+	}
+	report := &types.PruneReport{
+		DryRun:   true,
+		Deleted:  make([]string, 0),
+		Untagged: make([]string, 0),
+	}
+	for _, i := range images {
+		if used[i.ID] {
+			continue
+		}
+		tags := imageTags(i.RepoTags)
+		if dangling && len(tags) > 0 {
+			continue
+		}
+		if !until.IsZero() && !time.Unix(i.Created, 0).Before(until) {
+			continue
+		}
+		if !matchLabels(i.Labels, params.Labels, params.ExcludeLabels) {
+			continue
+		}
+		report.Untagged = append(report.Untagged, tags...)
+		report.Deleted = append(report.Deleted, i.ID)
+		report.SpaceReclaimed += uniqueSize(i)
+	}
+	return report, nil
+}
+
+// imageUniqueSizes maps every image, intermediate ones included, to the size
+// of the layers only it uses.
+func (app *App) imageUniqueSizes() (map[string]uint64, error) {
+	images, err := app.client.ImageList(context.Background(), image.ListOptions{
+		All:        true,
+		SharedSize: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]uint64, len(images))
+	for _, i := range images {
+		sizes[i.ID] = uniqueSize(i)
+	}
+	return sizes, nil
+}
+
+func uniqueSize(i image.Summary) uint64 {
+	size := i.Size
+	if i.SharedSize > 0 {
+		size -= i.SharedSize
+	}
+	if size < 0 {
+		return 0
+	}
+	return uint64(size)
+}
+
+// imageTags drops the <none> placeholders the daemon reports for untagged
+// images.
+func imageTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if tag != "<none>:<none>" && tag != "<none>@<none>" {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// imageDeleteReport splits the daemon's delete responses into deleted image
+// IDs and untagged refs, adding up the sizes of deleted images when known.
+func imageDeleteReport(resp []image.DeleteResponse, sizes map[string]uint64) *types.PruneReport {
+	report := &types.PruneReport{
+		Deleted:  make([]string, 0),
+		Untagged: make([]string, 0),
+	}
+	for _, r := range resp {
+		if r.Untagged != "" {
+			report.Untagged = append(report.Untagged, r.Untagged)
+		}
+		if r.Deleted != "" {
+			report.Deleted = append(report.Deleted, r.Deleted)
+			report.SpaceReclaimed += sizes[r.Deleted]
+		}
+	}
+	return report
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reactor/types"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// finished jobs are kept around this long so clients can still look up the
+// result after missing the socket events
+const jobRetention = time.Hour
+
+type jobEntry struct {
+	job    types.Job
+	nsp    string
+	cancel context.CancelFunc
+}
+
+// startJob runs fn in the background as a tracked job and broadcasts a
+// "job_status" event on the nsp socket namespace whenever its status changes.
+// The string returned by fn is stored as the job result.
+func (app *App) startJob(kind string, ref string, nsp string, fn func(ctx context.Context, id string) (string, error)) *types.Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &jobEntry{
+		job: types.Job{
+			ID:        uuid.NewString(),
+			Kind:      kind,
+			Ref:       ref,
+			Status:    JobRunning,
+			CreatedAt: time.Now(),
+		},
+		nsp:    nsp,
+		cancel: cancel,
+	}
+	app.lock.Lock()
+	if app.jobs == nil {
+		app.jobs = map[string]*jobEntry{}
+	}
+	for id, e := range app.jobs {
+		if e.job.FinishedAt != nil && time.Since(*e.job.FinishedAt) > jobRetention {
+			delete(app.jobs, id)
+		}
+	}
+	app.jobs[entry.job.ID] = entry
+	job := entry.job
+	app.lock.Unlock()
+	app.emitJob(nsp, "job_status", &job)
+
+	go func() {
+		defer cancel()
+		result, err := fn(ctx, job.ID)
+		now := time.Now()
+		app.lock.Lock()
+		entry.job.FinishedAt = &now
+		entry.job.Result = result
+		switch {
+		case ctx.Err() == context.Canceled:
+			entry.job.Status = JobCancelled
+		case err != nil:
+			entry.job.Status = JobFailed
+			entry.job.Error = err.Error()
+		default:
+			entry.job.Status = JobCompleted
+			entry.job.Progress = 100
+		}
+		finished := entry.job
+		app.lock.Unlock()
+		fmt.Println("[job]:", finished.Kind, finished.ID, finished.Status, finished.Error)
+		app.emitJob(nsp, "job_status", &finished)
+	}()
+	return &job
+}
+
+// setJobProgress records the overall progress of a running job in percent.
+func (app *App) setJobProgress(id string, progress float64) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if entry := app.jobs[id]; entry != nil {
+		entry.job.Progress = progress
+	}
+}
+
+// emitJob broadcasts an event to every client of a socket namespace. Clients
+// tell jobs apart by the job ID in the payload.
+func (app *App) emitJob(nsp string, event string, args ...any) {
+	if app.SocketServer == nil {
+		return
+	}
+	app.SocketServer.Of(nsp, nil).Emit(event, args...)
+}
+
+func (app *App) GetJob(id string) (*types.Job, bool) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	entry := app.jobs[id]
+	if entry == nil {
+		return nil, false
+	}
+	job := entry.job
+	return &job, true
+}
+func (app *App) ListJobs() []types.Job {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	jobs := make([]types.Job, 0, len(app.jobs))
+	for _, entry := range app.jobs {
+		jobs = append(jobs, entry.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// CancelJob aborts a running job through its context.
+func (app *App) CancelJob(id string) error {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	entry := app.jobs[id]
+	if entry == nil {
+		return fmt.Errorf("no job with id %s", id)
+	}
+	if entry.job.Status != JobRunning {
+		return fmt.Errorf("job %s is already %s", id, entry.job.Status)
+	}
+	entry.cancel()
+	return nil
+}
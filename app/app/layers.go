@@ -0,0 +1,454 @@
+package app
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reactor/types"
+	"slices"
+	"strings"
+)
+
+const (
+	layerFilesLimit    = 1000
+	layerFilesMaxLimit = 10000
+	wastedFilesLimit   = 50
+	// analyses are kept for the last few images so paging through layers
+	// does not save the image again for every request
+	layerAnalysesKept = 4
+	// manifests and configs are small, anything bigger is a layer
+	maxMetadataBlob = 8 << 20
+)
+
+// layerContents is what a single layer tarball changes: the entries it adds
+// and the paths it hides in the layers below, either one by one through
+// ".wh.<name>" whiteouts or a whole directory through an opaque marker.
+type layerContents struct {
+	files     []*layerFile
+	whiteouts []string
+	opaque    []string
+}
+
+// layerFile is an entry of a layer. digest identifies the content of regular
+// files and the target of links so files of equal size can still be told
+// apart.
+type layerFile struct {
+	path   string
+	mode   os.FileMode
+	size   int64
+	digest string
+}
+
+// layerAnalysis also keeps the filesystem all layers add up to, which is
+// what image diffs compare
+type layerAnalysis struct {
+	report  *types.ImageLayerReport
+	changes [][]types.ImageFileChange
+	files   map[string]*layerFile
+}
+
+// saveManifest is an entry of manifest.json in a docker save archive
+type saveManifest struct {
+	Config string
+	Layers []string
+}
+
+type imageConfig struct {
+	History []struct {
+		CreatedBy  string `json:"created_by"`
+		EmptyLayer bool   `json:"empty_layer"`
+	} `json:"history"`
+	RootFS struct {
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+// ImageLayers walks the layers of the image from a docker save archive and
+// summarises what each adds, modifies and deletes. Space taken by files that
+// a later layer overwrites or deletes is reported as wasted, with the worst
+// offenders listed.
+func (app *App) ImageLayers(id string) (*types.ImageLayerReport, error) {
+	analysis, err := app.analyzeImage(id)
+	if err != nil {
+		return nil, err
+	}
+	return analysis.report, nil
+}
+
+// ImageLayerFiles lists the file changes of a single layer, optionally only
+// those under query.Path or of one kind of change.
+func (app *App) ImageLayerFiles(params *types.ImageLayerRequestParams, query *types.ImageLayerFilesQuery) (*types.ImageLayerFiles, error) {
+	switch query.Change {
+	case "", "added", "modified", "deleted":
+	default:
+		return nil, fmt.Errorf("change: must be one of added, modified or deleted")
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = layerFilesLimit
+	}
+	if limit > layerFilesMaxLimit {
+		return nil, fmt.Errorf("limit: cannot exceed %d", layerFilesMaxLimit)
+	}
+	analysis, err := app.analyzeImage(params.ID)
+	if err != nil {
+		return nil, err
+	}
+	index := *params.Index
+	if index >= len(analysis.changes) {
+		return nil, fmt.Errorf("index: image has %d layers", len(analysis.changes))
+	}
+	prefix := ""
+	if query.Path != "" {
+		prefix = path.Clean("/" + query.Path)
+	}
+	files := &types.ImageLayerFiles{
+		Layer:   analysis.report.Layers[index],
+		Changes: make([]types.ImageFileChange, 0),
+	}
+	for _, change := range analysis.changes[index] {
+		if prefix != "" && prefix != "/" && change.Path != prefix && !strings.HasPrefix(change.Path, prefix+"/") {
+			continue
+		}
+		if query.Change != "" && change.Change != query.Change {
+			continue
+		}
+		files.Total++
+		if len(files.Changes) < limit {
+			files.Changes = append(files.Changes, change)
+		}
+	}
+	files.Truncated = files.Total > len(files.Changes)
+	return files, nil
+}
+
+func (app *App) analyzeImage(id string) (*layerAnalysis, error) {
+	i, _, err := app.client.ImageInspectWithRaw(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+	app.lock.Lock()
+	analysis, ok := app.layerAnalyses[i.ID]
+	app.lock.Unlock()
+	if ok {
+		return analysis, nil
+	}
+
+	rc, err := app.client.ImageSave(context.Background(), []string{i.ID})
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	analysis, err = readImageArchive(rc)
+	if err != nil {
+		return nil, err
+	}
+	analysis.report.ID = i.ID
+
+	app.lock.Lock()
+	if app.layerAnalyses == nil {
+		app.layerAnalyses = map[string]*layerAnalysis{}
+	}
+	for k := range app.layerAnalyses {
+		if len(app.layerAnalyses) < layerAnalysesKept {
+			break
+		}
+		delete(app.layerAnalyses, k)
+	}
+	app.layerAnalyses[i.ID] = analysis
+	app.lock.Unlock()
+	return analysis, nil
+}
+
+// readImageArchive analyses a docker save archive in a single pass. The
+// manifest naming the layers may come after the layers themselves, so every
+// layer tarball is read as it is found and small blobs are kept aside until
+// the manifest and config can be resolved. Both the legacy layout and the
+// OCI layout are supported.
+func readImageArchive(r io.Reader) (*layerAnalysis, error) {
+	layers := map[string]*layerContents{}
+	blobs := map[string][]byte{}
+	links := map[string]string{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(hdr.Name)
+		switch hdr.Typeflag {
+		case tar.TypeSymlink:
+			// the legacy layout links layers shared between images
+			links[name] = path.Join(path.Dir(name), hdr.Linkname)
+			continue
+		case tar.TypeReg:
+		default:
+			continue
+		}
+		br := bufio.NewReaderSize(tr, 1024)
+		head, _ := br.Peek(512)
+		if isTarHeader(head) || isGzip(head) {
+			layers[name], err = readLayer(br)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err.Error())
+			}
+			continue
+		}
+		if hdr.Size <= maxMetadataBlob {
+			blobs[name], err = io.ReadAll(br)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var manifests []saveManifest
+	if err := json.Unmarshal(blobs["manifest.json"], &manifests); err != nil || len(manifests) == 0 {
+		return nil, fmt.Errorf("archive has no image manifest")
+	}
+	manifest := manifests[0]
+	var config imageConfig
+	if err := json.Unmarshal(blobs[path.Clean(manifest.Config)], &config); err != nil {
+		return nil, fmt.Errorf("archive has no image config")
+	}
+	history := make([]string, 0, len(manifest.Layers))
+	for _, h := range config.History {
+		if !h.EmptyLayer {
+			history = append(history, h.CreatedBy)
+		}
+	}
+
+	contents := make([]*layerContents, len(manifest.Layers))
+	for i, name := range manifest.Layers {
+		name = path.Clean(name)
+		for range len(links) {
+			target, ok := links[name]
+			if !ok {
+				break
+			}
+			name = target
+		}
+		layer, ok := layers[name]
+		if !ok {
+			// an empty layer is all zeros and does not look like a tarball
+			blob, found := blobs[name]
+			if !found {
+				return nil, fmt.Errorf("archive is missing layer %s", name)
+			}
+			var err error
+			layer, err = readLayer(bytes.NewReader(blob))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", name, err.Error())
+			}
+		}
+		contents[i] = layer
+	}
+
+	analysis := analyzeLayers(contents)
+	for i := range analysis.report.Layers {
+		layer := &analysis.report.Layers[i]
+		if i < len(config.RootFS.DiffIDs) {
+			layer.Digest = config.RootFS.DiffIDs[i]
+		}
+		if i < len(history) {
+			layer.CreatedBy = history[i]
+			layer.Instruction, layer.Command = parseCreatedBy(history[i])
+		}
+	}
+	return analysis, nil
+}
+
+// analyzeLayers replays the layers on top of each other. Whiteouts only
+// apply to the layers below, so they are handled before the layer's own
+// entries. Directories are tracked but only reported when deleted.
+func analyzeLayers(contents []*layerContents) *layerAnalysis {
+	merged := map[string]*layerFile{}
+	wasted := map[string]*types.ImageWastedFile{}
+	waste := func(p string, size int64) {
+		w, ok := wasted[p]
+		if !ok {
+			w = &types.ImageWastedFile{Path: p}
+			wasted[p] = w
+		}
+		w.Count++
+		w.Size += size
+	}
+	analysis := &layerAnalysis{
+		report: &types.ImageLayerReport{
+			Layers: make([]types.ImageLayerSummary, len(contents)),
+			Wasted: make([]types.ImageWastedFile, 0),
+		},
+		changes: make([][]types.ImageFileChange, len(contents)),
+		files:   merged,
+	}
+	for i, layer := range contents {
+		summary := &analysis.report.Layers[i]
+		summary.Index = i
+		changes := make([]types.ImageFileChange, 0, len(layer.files))
+		// deleted removes the files under dir from the merged tree and
+		// returns their total size
+		deleted := func(dir string) int64 {
+			var total int64
+			prefix := strings.TrimSuffix(dir, "/") + "/"
+			for p, f := range merged {
+				if !strings.HasPrefix(p, prefix) {
+					continue
+				}
+				delete(merged, p)
+				if f.mode.IsDir() {
+					continue
+				}
+				changes = append(changes, types.ImageFileChange{Path: p, Change: "deleted", Type: "file", Size: f.size})
+				summary.Deleted++
+				summary.DeletedSize += f.size
+				waste(p, f.size)
+				total += f.size
+			}
+			return total
+		}
+		for _, dir := range layer.opaque {
+			deleted(dir)
+		}
+		for _, p := range layer.whiteouts {
+			f, ok := merged[p]
+			if !ok {
+				continue
+			}
+			delete(merged, p)
+			if f.mode.IsDir() {
+				changes = append(changes, types.ImageFileChange{Path: p, Change: "deleted", Type: "dir", Size: deleted(p)})
+				continue
+			}
+			changes = append(changes, types.ImageFileChange{Path: p, Change: "deleted", Type: "file", Size: f.size})
+			summary.Deleted++
+			summary.DeletedSize += f.size
+			waste(p, f.size)
+		}
+		for _, f := range layer.files {
+			prev, exists := merged[f.path]
+			merged[f.path] = f
+			if f.mode.IsDir() {
+				continue
+			}
+			change := types.ImageFileChange{
+				Path: f.path,
+				Type: fileType(f.mode),
+				Size: f.size,
+				Mode: f.mode.String(),
+			}
+			if exists && !prev.mode.IsDir() {
+				change.Change = "modified"
+				summary.Modified++
+				summary.ModifiedSize += f.size
+				waste(f.path, prev.size)
+			} else {
+				change.Change = "added"
+				summary.Added++
+				summary.AddedSize += f.size
+			}
+			changes = append(changes, change)
+			summary.Files++
+			summary.Size += f.size
+		}
+		slices.SortFunc(changes, func(a, b types.ImageFileChange) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		analysis.changes[i] = changes
+		analysis.report.Size += summary.Size
+	}
+
+	for _, w := range wasted {
+		if w.Size > 0 {
+			analysis.report.Wasted = append(analysis.report.Wasted, *w)
+			analysis.report.WastedSize += w.Size
+		}
+	}
+	slices.SortFunc(analysis.report.Wasted, func(a, b types.ImageWastedFile) int {
+		if a.Size != b.Size {
+			return cmp.Compare(b.Size, a.Size)
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	if len(analysis.report.Wasted) > wastedFilesLimit {
+		analysis.report.Wasted = analysis.report.Wasted[:wastedFilesLimit]
+	}
+	analysis.report.Efficiency = 100
+	if analysis.report.Size > 0 {
+		analysis.report.Efficiency = 100 - percentOf(analysis.report.WastedSize, analysis.report.Size)
+	}
+	return analysis
+}
+
+// readLayer lists the entries of a layer tarball, which may be gzipped
+func readLayer(r io.Reader) (*layerContents, error) {
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(2); isGzip(head) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+	layer := &layerContents{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return layer, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		p := path.Clean("/" + hdr.Name)
+		if p == "/" {
+			continue
+		}
+		dir, base := path.Split(p)
+		dir = path.Clean(dir)
+		switch {
+		case base == ".wh..wh..opq":
+			layer.opaque = append(layer.opaque, dir)
+		case strings.HasPrefix(base, ".wh."):
+			layer.whiteouts = append(layer.whiteouts, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+		default:
+			f := &layerFile{
+				path: p,
+				mode: hdr.FileInfo().Mode(),
+				size: hdr.Size,
+			}
+			switch hdr.Typeflag {
+			case tar.TypeReg:
+				h := sha256.New()
+				if _, err := io.Copy(h, tr); err != nil {
+					return nil, err
+				}
+				f.digest = fmt.Sprintf("sha256:%x", h.Sum(nil))
+			case tar.TypeSymlink, tar.TypeLink:
+				f.digest = "link:" + hdr.Linkname
+			}
+			layer.files = append(layer.files, f)
+		}
+	}
+}
+
+func isTarHeader(head []byte) bool {
+	return len(head) >= 262 && string(head[257:262]) == "ustar"
+}
+func isGzip(head []byte) bool {
+	return len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b
+}
@@ -0,0 +1,178 @@
+package app
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"math"
+	"reactor/types"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// layerTar builds a layer tarball from name and content pairs, names ending
+// in / are directories
+func layerTar(t *testing.T, compress bool, entries ...string) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	var tw *tar.Writer
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(buf)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(buf)
+	}
+	for i := 0; i < len(entries); i += 2 {
+		name, content := entries[i], entries[i+1]
+		hdr := &tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}
+		if strings.HasSuffix(name, "/") {
+			hdr.Typeflag, hdr.Mode, hdr.Size = tar.TypeDir, 0755, 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf
+}
+
+func TestReadLayer(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		layer, err := readLayer(layerTar(t, compress,
+			"./", "",
+			"etc/", "",
+			"etc/.wh.passwd", "",
+			"app/.wh..wh..opq", "",
+			".wh.tmp", "",
+			"app/main", "binary",
+		))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make([]string, 0)
+		for _, f := range layer.files {
+			files = append(files, f.path)
+		}
+		if fmt.Sprint(files) != "[/etc /app/main]" {
+			t.Errorf("gzip %v: files %v", compress, files)
+		}
+		if fmt.Sprint(layer.whiteouts) != "[/etc/passwd /tmp]" {
+			t.Errorf("gzip %v: whiteouts %v", compress, layer.whiteouts)
+		}
+		if fmt.Sprint(layer.opaque) != "[/app]" {
+			t.Errorf("gzip %v: opaque %v", compress, layer.opaque)
+		}
+		if layer.files[1].size != 6 || !strings.HasPrefix(layer.files[1].digest, "sha256:") {
+			t.Errorf("gzip %v: unexpected file %+v", compress, layer.files[1])
+		}
+	}
+}
+
+func TestAnalyzeLayers(t *testing.T) {
+	tarballs := []*bytes.Buffer{
+		layerTar(t, false,
+			"etc/", "",
+			"etc/a", strings.Repeat("a", 10),
+			"etc/b", strings.Repeat("b", 20),
+			"app/", "",
+			"app/x", strings.Repeat("x", 5),
+			"app/sub/", "",
+			"app/sub/y", strings.Repeat("y", 7),
+			"tmp/", "",
+			"tmp/big", strings.Repeat("1", 100),
+		),
+		layerTar(t, false,
+			"etc/.wh.a", "",
+			"app/.wh..wh..opq", "",
+			"app/z", "zzz",
+			"tmp/big", strings.Repeat("2", 50),
+			".wh.missing", "",
+		),
+		layerTar(t, false,
+			".wh.tmp", "",
+		),
+	}
+	contents := make([]*layerContents, 0, len(tarballs))
+	for _, tarball := range tarballs {
+		layer, err := readLayer(tarball)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents = append(contents, layer)
+	}
+	analysis := analyzeLayers(contents)
+
+	expectedLayers := []types.ImageLayerSummary{
+		{Index: 0, Size: 142, Files: 5, Added: 5, AddedSize: 142},
+		{Index: 1, Size: 53, Files: 2, Added: 1, AddedSize: 3, Modified: 1, ModifiedSize: 50, Deleted: 3, DeletedSize: 22},
+		{Index: 2, Deleted: 1, DeletedSize: 50},
+	}
+	for i, expected := range expectedLayers {
+		if analysis.report.Layers[i] != expected {
+			t.Errorf("layer %d: got %+v, want %+v", i, analysis.report.Layers[i], expected)
+		}
+	}
+
+	expectedChanges := [][]string{
+		{"added /app/sub/y", "added /app/x", "added /etc/a", "added /etc/b", "added /tmp/big"},
+		{"deleted /app/sub/y", "deleted /app/x", "added /app/z", "deleted /etc/a", "modified /tmp/big"},
+		{"deleted /tmp", "deleted /tmp/big"},
+	}
+	for i, expected := range expectedChanges {
+		changes := make([]string, 0)
+		for _, c := range analysis.changes[i] {
+			changes = append(changes, c.Change+" "+c.Path)
+		}
+		if fmt.Sprint(changes) != fmt.Sprint(expected) {
+			t.Errorf("layer %d: got changes %v, want %v", i, changes, expected)
+		}
+	}
+	if dir := analysis.changes[2][0]; dir.Type != "dir" || dir.Size != 50 {
+		t.Errorf("deleted directory reported as %+v", dir)
+	}
+
+	expectedWasted := []types.ImageWastedFile{
+		{Path: "/tmp/big", Count: 2, Size: 150},
+		{Path: "/etc/a", Count: 1, Size: 10},
+		{Path: "/app/sub/y", Count: 1, Size: 7},
+		{Path: "/app/x", Count: 1, Size: 5},
+	}
+	if fmt.Sprint(analysis.report.Wasted) != fmt.Sprint(expectedWasted) {
+		t.Errorf("got wasted %v, want %v", analysis.report.Wasted, expectedWasted)
+	}
+	if analysis.report.Size != 195 || analysis.report.WastedSize != 172 {
+		t.Errorf("size %d wasted %d, want 195 and 172", analysis.report.Size, analysis.report.WastedSize)
+	}
+	if math.Abs(analysis.report.Efficiency-(100-172.0/195*100)) > 1e-9 {
+		t.Errorf("efficiency %v", analysis.report.Efficiency)
+	}
+
+	files := make([]string, 0, len(analysis.files))
+	for p := range analysis.files {
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	if fmt.Sprint(files) != "[/app /app/z /etc /etc/b]" {
+		t.Errorf("merged filesystem %v", files)
+	}
+}
+
+func TestAnalyzeLayersEmpty(t *testing.T) {
+	analysis := analyzeLayers(nil)
+	if analysis.report.Efficiency != 100 || len(analysis.report.Wasted) != 0 {
+		t.Errorf("unexpected report %+v", analysis.report)
+	}
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reactor/types"
+
+	"github.com/docker/docker/api/types/network"
+)
+
+// networks the daemon creates itself and never removes
+var predefinedNetworks = map[string]bool{
+	"bridge": true,
+	"host":   true,
+	"none":   true,
+}
+
+// NetworkCreate creates a network and returns its ID along with any warning
+// from the daemon. IPv6 is turned on when any of the subnets is IPv6.
+func (app *App) NetworkCreate(params *types.NetworkCreateParams) (*network.CreateResponse, error) {
+	ipam, ipv6, err := parseIPAM(params.IPAM)
+	if err != nil {
+		return nil, err
+	}
+	opts := network.CreateOptions{
+		Driver:     params.Driver,
+		IPAM:       ipam,
+		Internal:   params.Internal,
+		Attachable: params.Attachable,
+		Options:    params.Options,
+		Labels:     params.Labels,
+	}
+	// left unset otherwise so the daemon's default applies
+	if params.EnableIPv6 || ipv6 {
+		enableIPv6 := true
+		opts.EnableIPv6 = &enableIPv6
+	}
+	res, err := app.client.NetworkCreate(context.Background(), params.Name, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (app *App) NetworkRemove(id string) error {
+	return app.client.NetworkRemove(context.Background(), id)
+}
+
+// NetworkConnect attaches a container to the network, optionally with extra
+// DNS aliases and static addresses. Static addresses need a network created
+// with a user-defined subnet.
+func (app *App) NetworkConnect(id string, params *types.NetworkConnectParams) error {
+	settings := &network.EndpointSettings{Aliases: params.Aliases}
+	if params.IPv4Address != "" || params.IPv6Address != "" {
+		if ip := net.ParseIP(params.IPv4Address); params.IPv4Address != "" && (ip == nil || ip.To4() == nil) {
+			return fmt.Errorf("ipv4_address: %q is not an IPv4 address", params.IPv4Address)
+		}
+		if ip := net.ParseIP(params.IPv6Address); params.IPv6Address != "" && (ip == nil || ip.To4() != nil) {
+			return fmt.Errorf("ipv6_address: %q is not an IPv6 address", params.IPv6Address)
+		}
+		settings.IPAMConfig = &network.EndpointIPAMConfig{
+			IPv4Address: params.IPv4Address,
+			IPv6Address: params.IPv6Address,
+		}
+	}
+	return app.client.NetworkConnect(context.Background(), id, params.Container, settings)
+}
+
+func (app *App) NetworkDisconnect(id string, params *types.NetworkDisconnectParams) error {
+	return app.client.NetworkDisconnect(context.Background(), id, params.Container, params.Force)
+}
+
+// NetworksPrune removes networks no container is attached to, matching the
+// filters in params. Networks hold no data, so no space is reclaimed.
+func (app *App) NetworksPrune(params *types.PruneParams) (*types.PruneReport, error) {
+	args, until, err := pruneFilters(params)
+	if err != nil {
+		return nil, err
+	}
+	if !params.DryRun {
+		report, err := app.client.NetworksPrune(context.Background(), args)
+		if err != nil {
+			return nil, err
+		}
+		deleted := report.NetworksDeleted
+		if deleted == nil {
+			deleted = make([]string, 0)
+		}
+		return &types.PruneReport{Deleted: deleted}, nil
+	}
+
+	networks, err := app.client.NetworkList(context.Background(), network.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	report := &types.PruneReport{
+		DryRun:  true,
+		Deleted: make([]string, 0),
+	}
+	for _, n := range networks {
+		if predefinedNetworks[n.Name] || n.Ingress {
+			continue
+		}
+		if !until.IsZero() && !n.Created.Before(until) {
+			continue
+		}
+		if !matchLabels(n.Labels, params.Labels, params.ExcludeLabels) {
+			continue
+		}
+		// the list leaves out attached containers, only inspect has them
+		i, err := app.client.NetworkInspect(context.Background(), n.ID, network.InspectOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if len(i.Containers) > 0 {
+			continue
+		}
+		report.Deleted = append(report.Deleted, n.Name)
+	}
+	return report, nil
+}
+
+// parseIPAM validates the subnets of a new network. Gateways and IP ranges
+// have to fall inside their subnet. It also reports whether any subnet is
+// IPv6.
+func parseIPAM(configs []types.NetworkIPAMConfig) (*network.IPAM, bool, error) {
+	if len(configs) == 0 {
+		return nil, false, nil
+	}
+	ipam := &network.IPAM{Config: make([]network.IPAMConfig, 0, len(configs))}
+	ipv6 := false
+	for i, c := range configs {
+		_, subnet, err := net.ParseCIDR(c.Subnet)
+		if err != nil {
+			return nil, false, fmt.Errorf("ipam[%d].subnet: %q is not a CIDR", i, c.Subnet)
+		}
+		if subnet.IP.To4() == nil {
+			ipv6 = true
+		}
+		if c.Gateway != "" {
+			gw := net.ParseIP(c.Gateway)
+			if gw == nil || !subnet.Contains(gw) {
+				return nil, false, fmt.Errorf("ipam[%d].gateway: %q is not an address in %s", i, c.Gateway, subnet)
+			}
+		}
+		if c.IPRange != "" {
+			_, r, err := net.ParseCIDR(c.IPRange)
+			if err != nil {
+				return nil, false, fmt.Errorf("ipam[%d].ip_range: %q is not a CIDR", i, c.IPRange)
+			}
+			subnetBits, _ := subnet.Mask.Size()
+			rangeBits, _ := r.Mask.Size()
+			if !subnet.Contains(r.IP) || rangeBits < subnetBits {
+				return nil, false, fmt.Errorf("ipam[%d].ip_range: %s is not inside %s", i, r, subnet)
+			}
+		}
+		ipam.Config = append(ipam.Config, network.IPAMConfig{
+			Subnet:  c.Subnet,
+			Gateway: c.Gateway,
+			IPRange: c.IPRange,
+		})
+	}
+	return ipam, ipv6, nil
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"reactor/types"
+	"testing"
+)
+
+func TestParseIPAM(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []types.NetworkIPAMConfig
+		ipv6    bool
+		fails   bool
+	}{
+		{name: "none"},
+		{name: "subnet", configs: []types.NetworkIPAMConfig{{Subnet: "172.28.0.0/16"}}},
+		{
+			name:    "gateway and range",
+			configs: []types.NetworkIPAMConfig{{Subnet: "172.28.0.0/16", Gateway: "172.28.0.1", IPRange: "172.28.5.0/24"}},
+		},
+		{name: "range equal to subnet", configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24", IPRange: "10.1.0.0/24"}}},
+		{
+			name:    "dual stack",
+			configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24"}, {Subnet: "2001:db8::/64", Gateway: "2001:db8::1"}},
+			ipv6:    true,
+		},
+		{name: "invalid subnet", configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0"}}, fails: true},
+		{name: "empty subnet", configs: []types.NetworkIPAMConfig{{Gateway: "10.1.0.1"}}, fails: true},
+		{name: "invalid gateway", configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24", Gateway: "gateway"}}, fails: true},
+		{name: "gateway outside", configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24", Gateway: "10.2.0.1"}}, fails: true},
+		{name: "invalid range", configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24", IPRange: "10.1.0.5"}}, fails: true},
+		{name: "range outside", configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24", IPRange: "10.2.0.0/24"}}, fails: true},
+		{name: "range wider", configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24", IPRange: "10.0.0.0/8"}}, fails: true},
+		{
+			name:    "second entry invalid",
+			configs: []types.NetworkIPAMConfig{{Subnet: "10.1.0.0/24"}, {Subnet: "10.2.0.0/33"}},
+			fails:   true,
+		},
+	}
+	for _, tt := range tests {
+		ipam, ipv6, err := parseIPAM(tt.configs)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err.Error())
+			continue
+		}
+		if ipv6 != tt.ipv6 {
+			t.Errorf("%s: ipv6 %v, want %v", tt.name, ipv6, tt.ipv6)
+		}
+		if len(tt.configs) == 0 {
+			if ipam != nil {
+				t.Errorf("%s: expected no ipam, got %+v", tt.name, ipam)
+			}
+			continue
+		}
+		if len(ipam.Config) != len(tt.configs) {
+			t.Errorf("%s: got %d configs, want %d", tt.name, len(ipam.Config), len(tt.configs))
+			continue
+		}
+		for i, c := range tt.configs {
+			got := ipam.Config[i]
+			if got.Subnet != c.Subnet || got.Gateway != c.Gateway || got.IPRange != c.IPRange {
+				t.Errorf("%s[%d]: got %+v, want %+v", tt.name, i, got, c)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reactor/types"
+	"reactor/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// ContainersPrune removes stopped containers matching the filters in params.
+// In dry-run mode nothing is removed and the report lists the containers that
+// would be, with the size of their writable layers as the reclaimable space.
+// Volume helpers are never pruned, they are removed once their request is done.
+func (app *App) ContainersPrune(params *types.PruneParams) (*types.PruneReport, error) {
+	args, until, err := pruneFilters(params)
+	if err != nil {
+		return nil, err
+	}
+	args.Add("label!", volumeHelperLabel)
+	if !params.DryRun {
+		report, err := app.client.ContainersPrune(context.Background(), args)
+		if err != nil {
+			return nil, err
+		}
+		deleted := report.ContainersDeleted
+		if deleted == nil {
+			deleted = make([]string, 0)
+		}
+		return &types.PruneReport{
+			Deleted:        deleted,
+			SpaceReclaimed: report.SpaceReclaimed,
+		}, nil
+	}
+
+	containers, err := app.client.ContainerList(context.Background(), container.ListOptions{
+		All:  true,
+		Size: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	report := &types.PruneReport{
+		DryRun:  true,
+		Deleted: make([]string, 0),
+	}
+	for _, c := range containers {
+		// prune only ever touches stopped containers
+		if c.State != "created" && c.State != "exited" && c.State != "dead" {
+			continue
+		}
+		if isVolumeHelper(&c) {
+			continue
+		}
+		if !until.IsZero() && !time.Unix(c.Created, 0).Before(until) {
+			continue
+		}
+		if !matchLabels(c.Labels, params.Labels, params.ExcludeLabels) {
+			continue
+		}
+		report.Deleted = append(report.Deleted, c.ID)
+		report.SpaceReclaimed += uint64(c.SizeRw)
+	}
+	return report, nil
+}
+
+// pruneFilters converts the prune params into daemon filters. until accepts a
+// duration relative to now such as "24h", an RFC 3339 timestamp or unix
+// seconds, and is returned resolved so dry runs apply the same cut-off.
+func pruneFilters(params *types.PruneParams) (filters.Args, time.Time, error) {
+	args := filters.NewArgs()
+	var until time.Time
+	if params.Until != "" {
+		d, err := time.ParseDuration(params.Until)
+		if err == nil {
+			until = time.Now().Add(-d)
+		} else {
+			until, err = utils.ParseTime(params.Until)
+			if err != nil {
+				return args, until, fmt.Errorf("until: %s", err.Error())
+			}
+		}
+		args.Add("until", strconv.FormatInt(until.Unix(), 10))
+	}
+	for i, label := range params.Labels {
+		if strings.HasPrefix(label, "=") || label == "" {
+			return args, until, fmt.Errorf("labels[%d]: %q is not in key or key=value form", i, label)
+		}
+		args.Add("label", label)
+	}
+	for i, label := range params.ExcludeLabels {
+		if strings.HasPrefix(label, "=") || label == "" {
+			return args, until, fmt.Errorf("exclude_labels[%d]: %q is not in key or key=value form", i, label)
+		}
+		args.Add("label!", label)
+	}
+	return args, until, nil
+}
+
+// matchLabels applies label and label! filters the way the daemon does: every
+// include must match and no exclude may match. Each filter is either a bare
+// key or key=value.
+func matchLabels(labels map[string]string, include []string, exclude []string) bool {
+	has := func(filter string) bool {
+		key, value, withValue := strings.Cut(filter, "=")
+		v, ok := labels[key]
+		if !withValue {
+			return ok
+		}
+		return ok && v == value
+	}
+	for _, filter := range include {
+		if !has(filter) {
+			return false
+		}
+	}
+	for _, filter := range exclude {
+		if has(filter) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"reactor/types"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPruneFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   types.PruneParams
+		until    time.Time
+		labels   []string
+		excludes []string
+		fails    bool
+	}{
+		{name: "empty"},
+		{
+			name:   "unix seconds",
+			params: types.PruneParams{Until: "1700000000"},
+			until:  time.Unix(1700000000, 0),
+		},
+		{
+			name:   "rfc 3339",
+			params: types.PruneParams{Until: "2024-01-02T03:04:05Z"},
+			until:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "labels",
+			params:   types.PruneParams{Labels: []string{"env=dev", "tmp"}, ExcludeLabels: []string{"keep"}},
+			labels:   []string{"env=dev", "tmp"},
+			excludes: []string{"keep"},
+		},
+		{name: "invalid until", params: types.PruneParams{Until: "yesterday"}, fails: true},
+		{name: "empty label", params: types.PruneParams{Labels: []string{""}}, fails: true},
+		{name: "label without key", params: types.PruneParams{ExcludeLabels: []string{"=dev"}}, fails: true},
+	}
+	for _, tt := range tests {
+		args, until, err := pruneFilters(&tt.params)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err.Error())
+			continue
+		}
+		if !until.Equal(tt.until) {
+			t.Errorf("%s: until %v, want %v", tt.name, until, tt.until)
+		}
+		if tt.until.IsZero() {
+			if args.Contains("until") {
+				t.Errorf("%s: unexpected until filter %v", tt.name, args.Get("until"))
+			}
+		} else if !args.ExactMatch("until", strconv.FormatInt(tt.until.Unix(), 10)) {
+			t.Errorf("%s: until filter %v", tt.name, args.Get("until"))
+		}
+		if len(args.Get("label")) != len(tt.labels) || len(args.Get("label!")) != len(tt.excludes) {
+			t.Errorf("%s: labels %v excludes %v", tt.name, args.Get("label"), args.Get("label!"))
+		}
+		for _, label := range tt.labels {
+			if !args.ExactMatch("label", label) {
+				t.Errorf("%s: missing label %q", tt.name, label)
+			}
+		}
+		for _, label := range tt.excludes {
+			if !args.ExactMatch("label!", label) {
+				t.Errorf("%s: missing exclude %q", tt.name, label)
+			}
+		}
+	}
+}
+
+func TestPruneFiltersDuration(t *testing.T) {
+	before := time.Now()
+	_, until, err := pruneFilters(&types.PruneParams{Until: "24h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now()
+	if until.Before(before.Add(-24*time.Hour)) || until.After(after.Add(-24*time.Hour)) {
+		t.Errorf("until is %v before now, want 24h", after.Sub(until))
+	}
+}
+
+func TestMatchLabels(t *testing.T) {
+	labels := map[string]string{"env": "dev", "tmp": "", "team": "a=b"}
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected bool
+	}{
+		{name: "no filters", expected: true},
+		{name: "key", include: []string{"env"}, expected: true},
+		{name: "key and value", include: []string{"env=dev"}, expected: true},
+		{name: "other value", include: []string{"env=prod"}},
+		{name: "missing key", include: []string{"owner"}},
+		{name: "empty value", include: []string{"tmp="}, expected: true},
+		{name: "value with =", include: []string{"team=a=b"}, expected: true},
+		{name: "all includes must match", include: []string{"env=dev", "owner"}},
+		{name: "excluded key", exclude: []string{"tmp"}},
+		{name: "excluded value", include: []string{"env"}, exclude: []string{"env=dev"}},
+		{name: "exclude other value", exclude: []string{"env=prod"}, expected: true},
+	}
+	for _, tt := range tests {
+		if matchLabels(labels, tt.include, tt.exclude) != tt.expected {
+			t.Errorf("%s: expected %v", tt.name, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reactor/types"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// ImagePull starts pulling an image as a background job and returns the job
+// right away. Per-layer "pull_progress" events are broadcast on the /image
+// namespace while the job runs; the job result is the pulled digest. A saved
+// credential for the registry host is used when there is one.
+func (app *App) ImagePull(params *types.ImagePullParams) (*types.Job, error) {
+	ref, err := imageRef(params.Repo, params.Tag)
+	if err != nil {
+		return nil, err
+	}
+	auth, err := app.registryAuth(ref)
+	if err != nil {
+		return nil, err
+	}
+	job := app.startJob("pull", ref, "/image", func(ctx context.Context, id string) (string, error) {
+		rc, err := app.client.ImagePull(ctx, ref, image.PullOptions{RegistryAuth: auth})
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		return app.trackProgress(id, "pull_progress", rc)
+	})
+	return job, nil
+}
+
+// imageRef joins a repository and an optional tag into an image reference.
+func imageRef(repo string, tag string) (string, error) {
+	if repo == "" {
+		return "", fmt.Errorf("repo: is required")
+	}
+	if tag == "" {
+		return repo, nil
+	}
+	if strings.Contains(repo, "@") {
+		return "", fmt.Errorf("tag: cannot be combined with a digest reference")
+	}
+	// a colon after the last slash is a tag, before it a registry port
+	if strings.Contains(repo[strings.LastIndex(repo, "/")+1:], ":") {
+		return "", fmt.Errorf("tag: repo %q already has a tag", repo)
+	}
+	return fmt.Sprintf("%s:%s", repo, tag), nil
+}
+
+var digestStatus = regexp.MustCompile(`(?i)digest: (\S+)`)
+
+// pullProgress keeps the state of every layer of a pull or push so an
+// overall percentage can be derived from the per-layer messages.
+type pullProgress struct {
+	layers map[string]float64
+	order  []string
+}
+
+// pulled layer progress is split 80/20 between downloading and extracting,
+// which is roughly how long the two phases take for typical layers. Pushed
+// layers only have the upload.
+func (p *pullProgress) update(msg *jsonmessage.JSONMessage) {
+	if _, ok := p.layers[msg.ID]; !ok {
+		p.order = append(p.order, msg.ID)
+	}
+	fraction := func() float64 {
+		if msg.Progress == nil || msg.Progress.Total <= 0 {
+			return 0
+		}
+		return float64(msg.Progress.Current) / float64(msg.Progress.Total)
+	}
+	switch msg.Status {
+	case "Downloading":
+		p.layers[msg.ID] = 0.8 * fraction()
+	case "Verifying Checksum", "Download complete":
+		p.layers[msg.ID] = 0.8
+	case "Extracting":
+		p.layers[msg.ID] = 0.8 + 0.2*fraction()
+	case "Pushing":
+		p.layers[msg.ID] = fraction()
+	case "Pull complete", "Already exists", "Pushed", "Layer already exists":
+		p.layers[msg.ID] = 1
+	default:
+		if strings.HasPrefix(msg.Status, "Mounted from") {
+			p.layers[msg.ID] = 1
+			return
+		}
+		if _, ok := p.layers[msg.ID]; !ok {
+			p.layers[msg.ID] = 0
+		}
+	}
+}
+func (p *pullProgress) percent() float64 {
+	if len(p.layers) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range p.layers {
+		total += v
+	}
+	return total / float64(len(p.layers)) * 100
+}
+
+// trackProgress decodes the JSON message stream of a pull or push,
+// broadcasting progress for job id as event, and returns the digest reported
+// at the end.
+func (app *App) trackProgress(id string, event string, r io.Reader) (string, error) {
+	progress := &pullProgress{layers: map[string]float64{}}
+	digest := ""
+	dec := json.NewDecoder(r)
+	for {
+		var msg jsonmessage.JSONMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			return digest, nil
+		}
+		if err != nil {
+			return digest, err
+		}
+		if msg.Error != nil {
+			return digest, msg.Error
+		}
+		// pulls end with "Digest: <digest>", pushes with
+		// "<tag>: digest: <digest> size: <size>"
+		if m := digestStatus.FindStringSubmatch(msg.Status); m != nil {
+			digest = m[1]
+		}
+		p := &types.ImagePullProgress{
+			JobID:  id,
+			Layer:  msg.ID,
+			Status: msg.Status,
+		}
+		// "Pulling from" carries the tag as its ID, it is not a layer
+		if msg.ID != "" && !strings.HasPrefix(msg.Status, "Pulling from") {
+			progress.update(&msg)
+			if msg.Progress != nil {
+				p.Current = msg.Progress.Current
+				p.Total = msg.Progress.Total
+			}
+		}
+		p.Percent = progress.percent()
+		app.setJobProgress(id, p.Percent)
+		app.emitJob("/image", event, p)
+	}
+}
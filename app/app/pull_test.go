@@ -0,0 +1,40 @@
+package app
+
+import "testing"
+
+func TestImageRef(t *testing.T) {
+	tests := []struct {
+		repo     string
+		tag      string
+		expected string
+		fails    bool
+	}{
+		{repo: "nginx", expected: "nginx"},
+		{repo: "nginx", tag: "1.27", expected: "nginx:1.27"},
+		{repo: "nginx:1.27", expected: "nginx:1.27"},
+		{repo: "docker.io/library/nginx", tag: "latest", expected: "docker.io/library/nginx:latest"},
+		{repo: "localhost:5000/app", tag: "dev", expected: "localhost:5000/app:dev"},
+		{repo: "localhost:5000/team/app", expected: "localhost:5000/team/app"},
+		{repo: "nginx@sha256:abc", expected: "nginx@sha256:abc"},
+		{repo: "", tag: "latest", fails: true},
+		{repo: "nginx:1.27", tag: "latest", fails: true},
+		{repo: "localhost:5000/app:dev", tag: "latest", fails: true},
+		{repo: "nginx@sha256:abc", tag: "latest", fails: true},
+	}
+	for _, tt := range tests {
+		ref, err := imageRef(tt.repo, tt.tag)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%q %q: expected an error, got %q", tt.repo, tt.tag, ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q %q: %s", tt.repo, tt.tag, err.Error())
+			continue
+		}
+		if ref != tt.expected {
+			t.Errorf("%q %q: got %q, want %q", tt.repo, tt.tag, ref, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reactor/models"
+	"reactor/types"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+)
+
+// Docker Hub credentials are sent with the legacy index address, as the
+// docker CLI does
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// ImagePush pushes a local image as a background job, authenticating with
+// the saved credential for the registry host of the ref. "push_progress"
+// events are broadcast on the /image namespace; the job result is the
+// digest the registry reported.
+func (app *App) ImagePush(params *types.ImagePushParams) (*types.Job, error) {
+	ref, err := imageRef(params.Repo, params.Tag)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := app.client.ImageInspectWithRaw(context.Background(), ref); err != nil {
+		return nil, err
+	}
+	auth, err := app.registryAuth(ref)
+	if err != nil {
+		return nil, err
+	}
+	job := app.startJob("push", ref, "/image", func(ctx context.Context, id string) (string, error) {
+		rc, err := app.client.ImagePush(ctx, ref, image.PushOptions{RegistryAuth: auth})
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		return app.trackProgress(id, "push_progress", rc)
+	})
+	return job, nil
+}
+
+// registryAuth encodes the saved credential for the registry the ref points
+// at. Without one the request goes out anonymously.
+func (app *App) registryAuth(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", fmt.Errorf("repo: %s", err.Error())
+	}
+	cred, ok := app.registryManager.FindCredential(reference.Domain(named))
+	if !ok {
+		return registry.EncodeAuthConfig(registry.AuthConfig{})
+	}
+	password, err := cred.Secret()
+	if err != nil {
+		return "", fmt.Errorf("credential for %s: %s", cred.Registry, err.Error())
+	}
+	server := cred.Registry
+	if server == "docker.io" {
+		server = dockerHubServer
+	}
+	return registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      cred.Username,
+		Password:      password,
+		ServerAddress: server,
+	})
+}
+
+func (app *App) ListRegistryCredentials() []models.RegistryCredential {
+	return app.registryManager.ListCredentials()
+}
+func (app *App) GetRegistryCredential(id string) (*models.RegistryCredential, bool) {
+	return app.registryManager.GetCredential(id)
+}
+func (app *App) SaveRegistryCredential(c *models.RegistryCredential) error {
+	return app.registryManager.SaveCredential(c)
+}
+func (app *App) UpdateRegistryCredential(id string, c *models.RegistryCredential) (*models.RegistryCredential, error) {
+	return app.registryManager.UpdateCredential(id, c)
+}
+func (app *App) DeleteRegistryCredential(id string) bool {
+	return app.registryManager.DeleteCredential(id) == 1
+}
+
+// TestRegistryCredential logs in to the registry with a saved credential
+func (app *App) TestRegistryCredential(id string) (bool, string) {
+	cred, ok := app.registryManager.GetCredential(id)
+	if !ok {
+		return false, "No credential found"
+	}
+	password, err := cred.Secret()
+	if err != nil {
+		return false, err.Error()
+	}
+	server := cred.Registry
+	if server == "docker.io" {
+		server = dockerHubServer
+	}
+	_, err = app.client.RegistryLogin(context.Background(), registry.AuthConfig{
+		Username:      cred.Username,
+		Password:      password,
+		ServerAddress: server,
+	})
+	if err != nil {
+		return false, err.Error()
+	}
+	return true, ""
+}
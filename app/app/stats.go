@@ -0,0 +1,293 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reactor/models"
+	"reactor/types"
+	"reactor/utils"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+)
+
+// ContainerStats takes a single stats sample of a container. The daemon waits
+// for a second sample so that the CPU usage can be computed.
+func (app *App) ContainerStats(params *types.ContainerStatsParams) (*types.ContainerStats, error) {
+	res, err := app.client.ContainerStats(context.Background(), params.ID, false)
+	if err != nil {
+		fmt.Println("[stats#error]:", err.Error())
+		return nil, err
+	}
+	defer res.Body.Close()
+	var raw container.StatsResponse
+	err = json.NewDecoder(res.Body).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+	return computeContainerStats(&raw, nil), nil
+}
+
+// ContainerStatsStream calls emit with a computed sample for every stats entry
+// the daemon produces, roughly once a second, until ctx is cancelled or the
+// container goes away.
+func (app *App) ContainerStatsStream(ctx context.Context, id string, emit func(*types.ContainerStats)) error {
+	res, err := app.client.ContainerStats(ctx, id, true)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	dec := json.NewDecoder(res.Body)
+	var prev *types.ContainerStats
+	for {
+		var raw container.StatsResponse
+		err := dec.Decode(&raw)
+		if err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		stats := computeContainerStats(&raw, prev)
+		emit(stats)
+		prev = stats
+	}
+}
+
+// computeContainerStats derives the metrics shown by `docker stats` from a raw
+// stats entry. prev is the previously computed sample of the same container and
+// is used for the network rates; it may be nil.
+func computeContainerStats(raw *container.StatsResponse, prev *types.ContainerStats) *types.ContainerStats {
+	stats := &types.ContainerStats{
+		ID:          raw.ID,
+		Name:        strings.TrimPrefix(raw.Name, "/"),
+		Read:        raw.Read,
+		MemoryLimit: raw.MemoryStats.Limit,
+		PIDs:        raw.PidsStats.Current,
+	}
+
+	onlineCPUs := raw.CPUStats.OnlineCPUs
+	if onlineCPUs == 0 {
+		onlineCPUs = uint32(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+	stats.OnlineCPUs = onlineCPUs
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * float64(onlineCPUs) * 100
+	}
+
+	// page cache is reclaimable, so it is not counted as used memory
+	usage := raw.MemoryStats.Usage
+	cache, ok := raw.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		cache = raw.MemoryStats.Stats["inactive_file"]
+	}
+	if cache < usage {
+		usage -= cache
+	}
+	stats.MemoryUsage = usage
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(usage) / float64(stats.MemoryLimit) * 100
+	}
+
+	for _, n := range raw.Networks {
+		stats.NetworkRx += n.RxBytes
+		stats.NetworkTx += n.TxBytes
+	}
+	if prev != nil {
+		elapsed := stats.Read.Sub(prev.Read).Seconds()
+		if elapsed > 0 && stats.NetworkRx >= prev.NetworkRx && stats.NetworkTx >= prev.NetworkTx {
+			stats.NetworkRxRate = float64(stats.NetworkRx-prev.NetworkRx) / elapsed
+			stats.NetworkTxRate = float64(stats.NetworkTx-prev.NetworkTx) / elapsed
+		}
+	}
+
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+	return stats
+}
+
+const (
+	statsSampleInterval = 10 * time.Second
+	statsRollupInterval = time.Minute
+)
+
+// runStatsSampler records a stats sample of every running container in the
+// history table and periodically rolls old samples up into aggregates.
+func (app *App) runStatsSampler() {
+	prev := map[string]*types.ContainerStats{}
+	sampleTicker := time.NewTicker(statsSampleInterval)
+	rollupTicker := time.NewTicker(statsRollupInterval)
+	defer sampleTicker.Stop()
+	defer rollupTicker.Stop()
+	for {
+		select {
+		case <-sampleTicker.C:
+			app.sampleRunningContainers(prev)
+		case now := <-rollupTicker.C:
+			err := app.statsManager.Rollup(now)
+			if err != nil {
+				fmt.Println("[stats#rollup]:", err.Error())
+			}
+			err = app.pruneRemovedStats(now)
+			if err != nil {
+				fmt.Println("[stats#prune]:", err.Error())
+			}
+		}
+	}
+}
+func (app *App) sampleRunningContainers(prev map[string]*types.ContainerStats) {
+	containers, err := app.client.ContainerList(context.Background(), container.ListOptions{})
+	if err != nil {
+		fmt.Println("[stats#sampler]:", err.Error())
+		return
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	current := map[string]*types.ContainerStats{}
+	for _, c := range containers {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			res, err := app.client.ContainerStats(context.Background(), id, false)
+			if err != nil {
+				fmt.Println("[stats#sampler]:", id, err.Error())
+				return
+			}
+			defer res.Body.Close()
+			var raw container.StatsResponse
+			err = json.NewDecoder(res.Body).Decode(&raw)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			current[id] = computeContainerStats(&raw, prev[id])
+		}(c.ID)
+	}
+	wg.Wait()
+
+	samples := make([]models.ContainerStatsSample, 0, len(current))
+	for id, stats := range current {
+		samples = append(samples, models.ContainerStatsSample{
+			ContainerID:   id,
+			Resolution:    models.StatsResolutionRaw,
+			Time:          stats.Read,
+			Samples:       1,
+			CPUPercent:    stats.CPUPercent,
+			MemoryUsage:   stats.MemoryUsage,
+			MemoryLimit:   stats.MemoryLimit,
+			MemoryPercent: stats.MemoryPercent,
+			NetworkRxRate: stats.NetworkRxRate,
+			NetworkTxRate: stats.NetworkTxRate,
+			BlockRead:     stats.BlockRead,
+			BlockWrite:    stats.BlockWrite,
+			PIDs:          stats.PIDs,
+		})
+	}
+	// containers that stopped are forgotten so their rates restart from zero
+	clear(prev)
+	for id, stats := range current {
+		prev[id] = stats
+	}
+	err = app.statsManager.SaveSamples(samples)
+	if err != nil {
+		fmt.Println("[stats#sampler]:", err.Error())
+	}
+}
+
+// ContainerStatsHistory returns the recorded samples of a container. from and
+// to accept RFC 3339 timestamps or unix seconds and default to the last hour.
+// An empty resolution picks one that keeps the number of points reasonable.
+// Removed containers keep their history for a while, but can then only be
+// looked up by ID.
+func (app *App) ContainerStatsHistory(params *types.ContainerStatsParams, query *types.ContainerStatsHistoryQuery) ([]models.ContainerStatsSample, error) {
+	id, err := app.statsContainerID(params.ID)
+	if err != nil {
+		return nil, err
+	}
+	to := time.Now()
+	if query.To != "" {
+		to, err = utils.ParseTime(query.To)
+		if err != nil {
+			return nil, fmt.Errorf("to: %s", err.Error())
+		}
+	}
+	from := to.Add(-time.Hour)
+	if query.From != "" {
+		from, err = utils.ParseTime(query.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %s", err.Error())
+		}
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from: must be before to")
+	}
+	resolution := query.Resolution
+	switch resolution {
+	case models.StatsResolutionRaw, models.StatsResolutionMinute, models.StatsResolutionHour:
+	case "":
+		span := to.Sub(from)
+		if span <= 2*time.Hour {
+			resolution = models.StatsResolutionRaw
+		} else if span <= 2*24*time.Hour {
+			resolution = models.StatsResolutionMinute
+		} else {
+			resolution = models.StatsResolutionHour
+		}
+	default:
+		return nil, fmt.Errorf("resolution: must be one of raw, 1m or 1h")
+	}
+	return app.statsManager.History(id, from, to, resolution)
+}
+
+// statsContainerID resolves a container name or ID to the full ID samples are
+// recorded under. Containers that no longer exist are matched by ID prefix
+// against the recorded samples instead.
+func (app *App) statsContainerID(ref string) (string, error) {
+	cjson, err := app.client.ContainerInspect(context.Background(), ref)
+	if err == nil {
+		return cjson.ID, nil
+	}
+	if !errdefs.IsNotFound(err) {
+		return "", err
+	}
+	ids, e := app.statsManager.ContainerIDs(ref)
+	if e != nil {
+		return "", e
+	}
+	switch len(ids) {
+	case 0:
+		return "", err
+	case 1:
+		return ids[0], nil
+	default:
+		return "", fmt.Errorf("id: %q matches the history of %d containers", ref, len(ids))
+	}
+}
+
+// pruneRemovedStats drops the history of containers that have been removed
+// for longer than the retention.
+func (app *App) pruneRemovedStats(now time.Time) error {
+	containers, err := app.client.ContainerList(context.Background(), container.ListOptions{All: true})
+	if err != nil {
+		return err
+	}
+	existing := make([]string, 0, len(containers))
+	for _, c := range containers {
+		existing = append(existing, c.ID)
+	}
+	return app.statsManager.PruneRemoved(existing, now)
+}
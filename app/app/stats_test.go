@@ -0,0 +1,150 @@
+package app
+
+import (
+	"encoding/json"
+	"math"
+	"reactor/types"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// rawStats decodes a stats entry the way it arrives from the daemon
+func rawStats(t *testing.T, doc string) *container.StatsResponse {
+	t.Helper()
+	var raw container.StatsResponse
+	if err := json.Unmarshal([]byte(doc), &raw); err != nil {
+		t.Fatal(err)
+	}
+	return &raw
+}
+
+func almostEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestComputeContainerStatsCPU(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		expected float64
+		cpus     uint32
+	}{
+		{
+			name: "online cpus",
+			doc: `{"cpu_stats": {"cpu_usage": {"total_usage": 400}, "system_cpu_usage": 2000, "online_cpus": 2},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 1000}}`,
+			expected: 40,
+			cpus:     2,
+		},
+		{
+			name: "per cpu fallback",
+			doc: `{"cpu_stats": {"cpu_usage": {"total_usage": 400, "percpu_usage": [100, 100, 100, 100]}, "system_cpu_usage": 2000},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 1000}}`,
+			expected: 80,
+			cpus:     4,
+		},
+		{
+			name: "idle",
+			doc: `{"cpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 2000, "online_cpus": 2},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 1000}}`,
+			expected: 0,
+			cpus:     2,
+		},
+		{
+			name: "no system delta",
+			doc: `{"cpu_stats": {"cpu_usage": {"total_usage": 400}, "system_cpu_usage": 1000, "online_cpus": 1},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 1000}}`,
+			expected: 0,
+			cpus:     1,
+		},
+	}
+	for _, tt := range tests {
+		stats := computeContainerStats(rawStats(t, tt.doc), nil)
+		if !almostEqual(stats.CPUPercent, tt.expected) {
+			t.Errorf("%s: cpu %v, want %v", tt.name, stats.CPUPercent, tt.expected)
+		}
+		if stats.OnlineCPUs != tt.cpus {
+			t.Errorf("%s: online cpus %d, want %d", tt.name, stats.OnlineCPUs, tt.cpus)
+		}
+	}
+}
+
+func TestComputeContainerStatsMemory(t *testing.T) {
+	tests := []struct {
+		name            string
+		doc             string
+		expectedUsage   uint64
+		expectedPercent float64
+	}{
+		{
+			name:            "cgroup v2",
+			doc:             `{"memory_stats": {"usage": 1000, "limit": 1600, "stats": {"inactive_file": 200}}}`,
+			expectedUsage:   800,
+			expectedPercent: 50,
+		},
+		{
+			name:            "cgroup v1",
+			doc:             `{"memory_stats": {"usage": 1000, "limit": 2000, "stats": {"total_inactive_file": 600, "inactive_file": 200}}}`,
+			expectedUsage:   400,
+			expectedPercent: 20,
+		},
+		{
+			name:            "cache above usage",
+			doc:             `{"memory_stats": {"usage": 100, "limit": 1000, "stats": {"inactive_file": 200}}}`,
+			expectedUsage:   100,
+			expectedPercent: 10,
+		},
+		{
+			name:          "no limit",
+			doc:           `{"memory_stats": {"usage": 100}}`,
+			expectedUsage: 100,
+		},
+	}
+	for _, tt := range tests {
+		stats := computeContainerStats(rawStats(t, tt.doc), nil)
+		if stats.MemoryUsage != tt.expectedUsage {
+			t.Errorf("%s: usage %d, want %d", tt.name, stats.MemoryUsage, tt.expectedUsage)
+		}
+		if !almostEqual(stats.MemoryPercent, tt.expectedPercent) {
+			t.Errorf("%s: percent %v, want %v", tt.name, stats.MemoryPercent, tt.expectedPercent)
+		}
+	}
+}
+
+func TestComputeContainerStatsIO(t *testing.T) {
+	read := time.Date(2024, 1, 1, 0, 0, 10, 0, time.UTC)
+	doc := `{"id": "abc", "name": "/web", "read": "2024-01-01T00:00:10Z", "pids_stats": {"current": 7},
+		"networks": {"eth0": {"rx_bytes": 3000, "tx_bytes": 500}, "eth1": {"rx_bytes": 1000, "tx_bytes": 500}},
+		"blkio_stats": {"io_service_bytes_recursive": [
+			{"op": "Read", "value": 10}, {"op": "write", "value": 20}, {"op": "read", "value": 5}, {"op": "Total", "value": 35}]}}`
+	raw := rawStats(t, doc)
+
+	stats := computeContainerStats(raw, nil)
+	if stats.ID != "abc" || stats.Name != "web" || stats.PIDs != 7 || !stats.Read.Equal(read) {
+		t.Errorf("unexpected identity %q %q %d %v", stats.ID, stats.Name, stats.PIDs, stats.Read)
+	}
+	if stats.NetworkRx != 4000 || stats.NetworkTx != 1000 {
+		t.Errorf("network %d/%d, want 4000/1000", stats.NetworkRx, stats.NetworkTx)
+	}
+	if stats.NetworkRxRate != 0 || stats.NetworkTxRate != 0 {
+		t.Errorf("rates without a previous sample: %v/%v", stats.NetworkRxRate, stats.NetworkTxRate)
+	}
+	if stats.BlockRead != 15 || stats.BlockWrite != 20 {
+		t.Errorf("block io %d/%d, want 15/20", stats.BlockRead, stats.BlockWrite)
+	}
+
+	prev := &types.ContainerStats{Read: read.Add(-2 * time.Second), NetworkRx: 2000, NetworkTx: 600}
+	stats = computeContainerStats(raw, prev)
+	if !almostEqual(stats.NetworkRxRate, 1000) || !almostEqual(stats.NetworkTxRate, 200) {
+		t.Errorf("rates %v/%v, want 1000/200", stats.NetworkRxRate, stats.NetworkTxRate)
+	}
+
+	// counters reset when the container restarts
+	prev = &types.ContainerStats{Read: read.Add(-2 * time.Second), NetworkRx: 5000, NetworkTx: 600}
+	stats = computeContainerStats(raw, prev)
+	if stats.NetworkRxRate != 0 || stats.NetworkTxRate != 0 {
+		t.Errorf("rates after a reset %v/%v, want 0", stats.NetworkRxRate, stats.NetworkTxRate)
+	}
+}
@@ -0,0 +1,37 @@
+package app
+
+import (
+	"reactor/types"
+
+	"github.com/zishang520/socket.io/socket"
+)
+
+// frameWriter turns everything written to it into a StreamFrame tagged with
+// the stream it came from. It is used as the destination for stdcopy when
+// demultiplexing docker streams.
+type frameWriter struct {
+	id     string
+	stream string
+	emit   func(*types.StreamFrame)
+}
+
+func (w *frameWriter) Write(p []byte) (int, error) {
+	w.emit(&types.StreamFrame{
+		ID:     w.id,
+		Stream: w.stream,
+		Data:   string(p),
+	})
+	return len(p), nil
+}
+
+// newSocketWriter returns a frameWriter that emits each frame on the given
+// socket event.
+func newSocketWriter(client *socket.Socket, event string, id string, stream string) *frameWriter {
+	return &frameWriter{
+		id:     id,
+		stream: stream,
+		emit: func(frame *types.StreamFrame) {
+			client.Emit(event, frame)
+		},
+	}
+}
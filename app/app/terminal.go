@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reactor/types"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/zishang520/socket.io/socket"
+)
+
+const defaultTerminalShell = "/bin/sh"
+
+// ContainerTerminal starts an interactive exec session inside the container and
+// streams its output to the client as "output" frames. The session is tracked
+// in AttachedExecs under the returned exec ID until CloseTerminal is called.
+func (app *App) ContainerTerminal(client *socket.Socket, params *types.ContainerTerminalParams) (string, error) {
+	if params.ID == "" {
+		return "", fmt.Errorf("id: container id is required")
+	}
+	cmd := params.Cmd
+	if len(cmd) == 0 {
+		cmd = []string{defaultTerminalShell}
+	}
+	tty := !params.NoTty
+	var consoleSize *[2]uint
+	if tty && params.Rows > 0 && params.Cols > 0 {
+		consoleSize = &[2]uint{params.Rows, params.Cols}
+	}
+	exec, err := app.client.ContainerExecCreate(context.Background(), params.ID, container.ExecOptions{
+		Tty:          tty,
+		ConsoleSize:  consoleSize,
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          cmd,
+		Env:          params.Env,
+		User:         params.User,
+		WorkingDir:   params.WorkingDir,
+	})
+	if err != nil {
+		fmt.Println("[terminal#create]:", err.Error())
+		return "", err
+	}
+	hj, err := app.client.ContainerExecAttach(context.Background(), exec.ID, container.ExecAttachOptions{
+		Tty:         tty,
+		ConsoleSize: consoleSize,
+	})
+	if err != nil {
+		fmt.Println("[terminal#attach]:", err.Error())
+		return "", err
+	}
+	app.setAttachedExec(exec.ID, &hj)
+
+	go func() {
+		defer app.CloseTerminal(exec.ID)
+
+		stdout := newSocketWriter(client, "output", exec.ID, "stdout")
+		stderr := newSocketWriter(client, "output", exec.ID, "stderr")
+		err := demuxStream(hj.Reader, tty, stdout, stderr)
+		if err != nil {
+			fmt.Println("[terminal#stream]:", err.Error())
+		}
+		exitCode := -1
+		inspect, err := app.client.ContainerExecInspect(context.Background(), exec.ID)
+		if err == nil {
+			exitCode = inspect.ExitCode
+		}
+		client.Emit("exit", exec.ID, exitCode)
+	}()
+
+	return exec.ID, nil
+}
+
+// TerminalInput writes raw keystrokes to the stdin of an open terminal session.
+func (app *App) TerminalInput(params *types.ContainerTerminalInputParams) error {
+	hj := app.getAttachedExec(params.ExecID)
+	if hj == nil {
+		return dockertypes.ErrorResponse{
+			Message: "terminal session is not connected",
+		}
+	}
+	_, err := hj.Conn.Write([]byte(params.Data))
+	return err
+}
+
+// TerminalResize resizes the tty of an open terminal session.
+func (app *App) TerminalResize(params *types.ContainerTerminalResizeParams) error {
+	if app.getAttachedExec(params.ExecID) == nil {
+		return dockertypes.ErrorResponse{
+			Message: "terminal session is not connected",
+		}
+	}
+	return app.client.ContainerExecResize(context.Background(), params.ExecID, container.ResizeOptions{
+		Height: params.Rows,
+		Width:  params.Cols,
+	})
+}
+
+// CloseTerminal closes the hijacked connection of a terminal session and stops
+// tracking it. Closing an unknown or already closed session is a no-op.
+func (app *App) CloseTerminal(execID string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	hj := app.AttachedExecs[execID]
+	if hj == nil {
+		return
+	}
+	hj.Close()
+	delete(app.AttachedExecs, execID)
+}
+
+func (app *App) setAttachedExec(id string, hj *dockertypes.HijackedResponse) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.AttachedExecs[id] = hj
+}
+func (app *App) getAttachedExec(id string) *dockertypes.HijackedResponse {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return app.AttachedExecs[id]
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reactor/types"
+	"strconv"
+	"strings"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Topology describes how the host's workloads are wired together. Nodes are
+// containers, networks, volumes and published host ports; edges point from a
+// container to the networks it is attached to, the volumes it mounts and the
+// host ports it publishes. Node IDs are prefixed with their kind so they are
+// unique across kinds.
+func (app *App) Topology() (*types.Topology, error) {
+	ctx := context.Background()
+	containers, err := app.client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	networks, err := app.client.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	volumes, err := app.client.VolumeList(ctx, volume.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	topology := &types.Topology{
+		Nodes: make([]types.TopologyNode, 0),
+		Edges: make([]types.TopologyEdge, 0),
+	}
+	seen := map[string]bool{}
+	addNode := func(node types.TopologyNode) {
+		if seen[node.ID] {
+			return
+		}
+		seen[node.ID] = true
+		topology.Nodes = append(topology.Nodes, node)
+	}
+	// containers that never ran have no network IDs on their endpoints yet
+	networkIDs := make(map[string]string, len(networks))
+	for _, n := range networks {
+		networkIDs[n.Name] = n.ID
+		addNode(types.TopologyNode{
+			ID:   "network:" + n.ID,
+			Kind: "network",
+			Name: n.Name,
+			Meta: map[string]string{
+				"driver":   n.Driver,
+				"scope":    n.Scope,
+				"internal": strconv.FormatBool(n.Internal),
+			},
+		})
+	}
+	for _, v := range volumes.Volumes {
+		addNode(types.TopologyNode{
+			ID:   "volume:" + v.Name,
+			Kind: "volume",
+			Name: v.Name,
+			Meta: map[string]string{
+				"driver": v.Driver,
+				"scope":  v.Scope,
+			},
+		})
+	}
+	for _, c := range containers {
+		if isVolumeHelper(&c) {
+			continue
+		}
+		id := "container:" + c.ID
+		addNode(types.TopologyNode{
+			ID:   id,
+			Kind: "container",
+			Name: containerName(&c),
+			Meta: map[string]string{
+				"image":  c.Image,
+				"state":  c.State,
+				"status": c.Status,
+			},
+		})
+		if c.NetworkSettings != nil {
+			for name, endpoint := range c.NetworkSettings.Networks {
+				networkID := endpoint.NetworkID
+				if networkID == "" {
+					networkID = networkIDs[name]
+				}
+				target := "network:" + networkID
+				if networkID == "" {
+					target = "network:" + name
+				}
+				if !seen[target] {
+					// created after the network list was taken
+					addNode(types.TopologyNode{ID: target, Kind: "network", Name: name, Meta: map[string]string{}})
+				}
+				topology.Edges = append(topology.Edges, types.TopologyEdge{
+					Source: id,
+					Target: target,
+					Kind:   "attached-to",
+					Label:  endpoint.IPAddress,
+				})
+			}
+		}
+		for _, m := range c.Mounts {
+			if m.Type != mount.TypeVolume {
+				continue
+			}
+			target := "volume:" + m.Name
+			if !seen[target] {
+				addNode(types.TopologyNode{ID: target, Kind: "volume", Name: m.Name, Meta: map[string]string{"driver": m.Driver}})
+			}
+			label := m.Destination
+			if !m.RW {
+				label += ":ro"
+			}
+			topology.Edges = append(topology.Edges, types.TopologyEdge{
+				Source: id,
+				Target: target,
+				Kind:   "mounts",
+				Label:  label,
+			})
+		}
+		for _, p := range c.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			hostPort := fmt.Sprintf("%s/%s", net.JoinHostPort(p.IP, strconv.Itoa(int(p.PublicPort))), p.Type)
+			target := "port:" + hostPort
+			addNode(types.TopologyNode{
+				ID:   target,
+				Kind: "port",
+				Name: hostPort,
+				Meta: map[string]string{
+					"ip":       p.IP,
+					"port":     strconv.Itoa(int(p.PublicPort)),
+					"protocol": p.Type,
+				},
+			})
+			topology.Edges = append(topology.Edges, types.TopologyEdge{
+				Source: id,
+				Target: target,
+				Kind:   "publishes",
+				Label:  fmt.Sprintf("%d/%s", p.PrivatePort, p.Type),
+			})
+		}
+	}
+	return topology, nil
+}
+
+// containerName is the primary name of a listed container without the
+// leading slash, or its short ID if it has none
+func containerName(c *dockertypes.Container) string {
+	if len(c.Names) == 0 {
+		return c.ID[:12]
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
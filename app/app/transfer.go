@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reactor/types"
+	"strings"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// ImageSave streams the images as a single tar archive in the docker save
+// format. refs may also hold comma-separated lists. The caller closes the
+// returned reader.
+func (app *App) ImageSave(ctx context.Context, query *types.ImageSaveQuery) (io.ReadCloser, error) {
+	refs := make([]string, 0, len(query.Refs))
+	for _, ref := range query.Refs {
+		for _, r := range strings.Split(ref, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				refs = append(refs, r)
+			}
+		}
+	}
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("refs: is required")
+	}
+	// fail before the response starts rather than halfway through the stream
+	for i, ref := range refs {
+		_, _, err := app.client.ImageInspectWithRaw(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("refs[%d]: %s", i, err.Error())
+		}
+	}
+	return app.client.ImageSave(ctx, refs)
+}
+
+// ImageLoad streams a docker save archive into the daemon and returns the
+// loaded refs, or the image IDs of images saved without a tag.
+func (app *App) ImageLoad(ctx context.Context, r io.Reader) ([]string, error) {
+	res, err := app.client.ImageLoad(ctx, r, true)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	loaded := make([]string, 0)
+	dec := json.NewDecoder(res.Body)
+	for {
+		var msg jsonmessage.JSONMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			return loaded, nil
+		}
+		if err != nil {
+			return loaded, err
+		}
+		if msg.Error != nil {
+			return loaded, msg.Error
+		}
+		line := strings.TrimSpace(msg.Stream)
+		if ref, found := strings.CutPrefix(line, "Loaded image: "); found {
+			loaded = append(loaded, ref)
+		} else if id, found := strings.CutPrefix(line, "Loaded image ID: "); found {
+			loaded = append(loaded, id)
+		}
+	}
+}
+
+// ImageImport creates a single-layer image from a rootfs tarball such as a
+// container export and returns the new image ID and its ref, if tagged.
+func (app *App) ImageImport(ctx context.Context, query *types.ImageImportQuery, r io.Reader) (string, string, error) {
+	ref := ""
+	if query.Repo != "" || query.Tag != "" {
+		var err error
+		ref, err = imageRef(query.Repo, query.Tag)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	err := checkChanges(query.Changes)
+	if err != nil {
+		return "", "", err
+	}
+	rc, err := app.client.ImageImport(ctx, image.ImportSource{Source: r, SourceName: "-"}, ref, image.ImportOptions{
+		Message:  query.Message,
+		Changes:  query.Changes,
+		Platform: query.Platform,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	defer rc.Close()
+	id := ""
+	dec := json.NewDecoder(rc)
+	for {
+		var msg jsonmessage.JSONMessage
+		err := dec.Decode(&msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if msg.Error != nil {
+			return "", "", msg.Error
+		}
+		// the last status is the ID of the imported image
+		if strings.HasPrefix(msg.Status, "sha256:") {
+			id = msg.Status
+		}
+	}
+	if id == "" {
+		return "", "", fmt.Errorf("import finished without an image ID")
+	}
+	return id, ref, nil
+}
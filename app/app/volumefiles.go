@@ -0,0 +1,229 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"reactor/types"
+	"strconv"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+// Volume files are read and written through the archive API of a helper
+// container that mounts the volume. The container is only created, never
+// started, so its image needs no particular shell or tools. Helpers carry
+// volumeHelperLabel and are left out of container listings. They also record
+// the instance that created them and when, so that leftovers can be swept
+// without touching helpers that are still in use or belong to another
+// instance sharing the daemon.
+const (
+	volumeMountPath          = "/volume"
+	volumeHelperLabel        = "reactor.volume-helper"
+	volumeHelperOwnerLabel   = "reactor.volume-helper.owner"
+	volumeHelperCreatedLabel = "reactor.volume-helper.created"
+	volumeHelperGracePeriod  = 15 * time.Minute
+)
+
+// VolumeListFiles lists the entries directly under query.Path of a volume.
+// Paths are relative to the root of the volume.
+func (app *App) VolumeListFiles(params *types.VolumeRequestParams, query *types.ContainerFilesQuery) (*types.FileListing, error) {
+	if query.Path == "" {
+		return nil, fmt.Errorf("path: is required")
+	}
+	id, cleanup, err := app.volumeHelper(params.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	p := volumeRel(query.Path)
+	return app.listFiles(id, volumePath(p), p)
+}
+
+// VolumePreviewFile returns up to query.Limit bytes of a regular file in a
+// volume.
+func (app *App) VolumePreviewFile(params *types.VolumeRequestParams, query *types.ContainerFilesQuery) (*types.FilePreview, error) {
+	if query.Path == "" {
+		return nil, fmt.Errorf("path: is required")
+	}
+	id, cleanup, err := app.volumeHelper(params.ID, true)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	p := volumeRel(query.Path)
+	return app.previewFile(id, volumeMountPath, volumePath(p), p, query.Limit)
+}
+
+// VolumeGetArchive returns a tar stream of the file or directory at query.Path
+// in a volume together with its stat. The helper container is removed when
+// the stream is closed, so the caller must close it.
+func (app *App) VolumeGetArchive(params *types.VolumeRequestParams, query *types.ContainerArchiveQuery) (io.ReadCloser, *container.PathStat, error) {
+	id, cleanup, err := app.volumeHelper(params.ID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, stat, err := app.client.CopyFromContainer(context.Background(), id, volumePath(query.Path))
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	// the root of the volume is named after the mount point otherwise
+	if stat.Name == path.Base(volumeMountPath) && volumeRel(query.Path) == "/" {
+		stat.Name = params.ID
+	}
+	return &helperStream{ReadCloser: rc, cleanup: cleanup}, &stat, nil
+}
+
+// VolumePutArchive extracts a tar stream into the directory at query.Path in
+// a volume.
+func (app *App) VolumePutArchive(params *types.VolumeRequestParams, query *types.ContainerArchiveQuery, content io.Reader) error {
+	id, cleanup, err := app.volumeHelper(params.ID, false)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	return app.client.CopyToContainer(context.Background(), id, volumePath(query.Path), content, container.CopyToContainerOptions{
+		AllowOverwriteDirWithFile: query.AllowOverwriteDirNonDir,
+		CopyUIDGID:                query.CopyUIDGID,
+	})
+}
+
+// volumeHelper creates a container with the volume mounted at
+// volumeMountPath, pulling the helper image first if it is missing. cleanup
+// removes the container again and leaves the volume alone.
+func (app *App) volumeHelper(name string, readOnly bool) (string, func(), error) {
+	ctx := context.Background()
+	if _, err := app.client.VolumeInspect(ctx, name); err != nil {
+		return "", nil, err
+	}
+	helperImage := app.configManager.GetVolumeHelperImage()
+	if _, _, err := app.client.ImageInspectWithRaw(ctx, helperImage); err != nil {
+		if !errdefs.IsNotFound(err) {
+			return "", nil, err
+		}
+		if err := app.pullHelperImage(ctx, helperImage); err != nil {
+			return "", nil, fmt.Errorf("pulling volume helper image %s: %s, set REACTOR_VOLUME_HELPER_IMAGE to an image the daemon has", helperImage, err.Error())
+		}
+	}
+	res, err := app.client.ContainerCreate(ctx, &container.Config{
+		Image:           helperImage,
+		NetworkDisabled: true,
+		Labels: map[string]string{
+			volumeHelperLabel:        name,
+			volumeHelperOwnerLabel:   app.instanceID,
+			volumeHelperCreatedLabel: strconv.FormatInt(time.Now().Unix(), 10),
+		},
+	}, &container.HostConfig{
+		NetworkMode: network.NetworkNone,
+		Mounts: []mount.Mount{
+			{
+				Type:     mount.TypeVolume,
+				Source:   name,
+				Target:   volumeMountPath,
+				ReadOnly: readOnly,
+			},
+		},
+	}, nil, nil, "")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		err := app.client.ContainerRemove(context.Background(), res.ID, container.RemoveOptions{Force: true})
+		if err != nil {
+			app.Logger.Println("cannot remove volume helper:", err.Error())
+		}
+	}
+	return res.ID, cleanup, nil
+}
+
+// removeVolumeHelpers removes helper containers left behind by a previous run
+// of this instance that stopped before cleaning up after itself. Helpers
+// younger than volumeHelperGracePeriod are kept, as another process sharing
+// the config path may still be using them.
+func (app *App) removeVolumeHelpers() {
+	if app.instanceID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	containers, err := app.client.ContainerList(ctx, container.ListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", volumeHelperOwnerLabel+"="+app.instanceID)),
+	})
+	if err != nil {
+		app.Logger.Println("cannot list volume helpers:", err.Error())
+		return
+	}
+	for _, c := range containers {
+		if !volumeHelperExpired(c.Labels, time.Now()) {
+			continue
+		}
+		err := app.client.ContainerRemove(ctx, c.ID, container.RemoveOptions{Force: true})
+		if err != nil {
+			app.Logger.Println("cannot remove volume helper:", err.Error())
+		}
+	}
+}
+
+// volumeHelperExpired reports whether a helper was created more than
+// volumeHelperGracePeriod before now. Helpers without a valid creation time
+// are never considered expired.
+func volumeHelperExpired(labels map[string]string, now time.Time) bool {
+	created, err := strconv.ParseInt(labels[volumeHelperCreatedLabel], 10, 64)
+	if err != nil {
+		return false
+	}
+	return now.Sub(time.Unix(created, 0)) > volumeHelperGracePeriod
+}
+
+// isVolumeHelper reports whether a container is a volume helper
+func isVolumeHelper(c *dockertypes.Container) bool {
+	_, ok := c.Labels[volumeHelperLabel]
+	return ok
+}
+
+func (app *App) pullHelperImage(ctx context.Context, ref string) error {
+	auth, err := app.registryAuth(ref)
+	if err != nil {
+		return err
+	}
+	rc, err := app.client.ImagePull(ctx, ref, image.PullOptions{RegistryAuth: auth})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// the pull is only done once the stream has been read to the end
+	return jsonmessage.DisplayJSONMessagesStream(rc, io.Discard, 0, false, nil)
+}
+
+// helperStream removes the helper container once the archive is closed
+type helperStream struct {
+	io.ReadCloser
+	cleanup func()
+}
+
+func (s *helperStream) Close() error {
+	err := s.ReadCloser.Close()
+	s.cleanup()
+	return err
+}
+
+// volumeRel cleans a path given relative to the root of a volume
+func volumeRel(p string) string {
+	return path.Clean("/" + p)
+}
+
+// volumePath maps a path in a volume to where the helper container mounts it
+func volumePath(p string) string {
+	return path.Join(volumeMountPath, volumeRel(p))
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVolumeHelperExpired(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	created := func(age time.Duration) string {
+		return strconv.FormatInt(now.Add(-age).Unix(), 10)
+	}
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected bool
+	}{
+		{name: "past the grace period", labels: map[string]string{volumeHelperCreatedLabel: created(volumeHelperGracePeriod + time.Second)}, expected: true},
+		{name: "within the grace period", labels: map[string]string{volumeHelperCreatedLabel: created(time.Minute)}},
+		{name: "at the grace period", labels: map[string]string{volumeHelperCreatedLabel: created(volumeHelperGracePeriod)}},
+		{name: "created in the future", labels: map[string]string{volumeHelperCreatedLabel: created(-time.Hour)}},
+		{name: "no creation time", labels: map[string]string{volumeHelperLabel: "data"}},
+		{name: "invalid creation time", labels: map[string]string{volumeHelperCreatedLabel: "yesterday"}},
+	}
+	for _, tt := range tests {
+		if volumeHelperExpired(tt.labels, now) != tt.expected {
+			t.Errorf("%s: expected %v", tt.name, tt.expected)
+		}
+	}
+}
+
+func TestVolumePath(t *testing.T) {
+	tests := []struct {
+		p        string
+		expected string
+	}{
+		{p: "", expected: "/volume"},
+		{p: "/", expected: "/volume"},
+		{p: "data/file", expected: "/volume/data/file"},
+		{p: "/data/../file", expected: "/volume/file"},
+		{p: "../../etc/passwd", expected: "/volume/etc/passwd"},
+	}
+	for _, tt := range tests {
+		if p := volumePath(tt.p); p != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.p, p, tt.expected)
+		}
+	}
+}
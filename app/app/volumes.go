@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"reactor/types"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// the daemon labels volumes created without a name with this
+const anonymousVolumeLabel = "com.docker.volume.anonymous"
+
+func (app *App) VolumeCreate(params *types.VolumeCreateParams) (*volume.Volume, error) {
+	v, err := app.client.VolumeCreate(context.Background(), volume.CreateOptions{
+		Name:       params.Name,
+		Driver:     params.Driver,
+		DriverOpts: params.DriverOpts,
+		Labels:     params.Labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// VolumeRemove removes a volume. force only skips errors from the volume
+// driver; a volume in use by a container is never removed.
+func (app *App) VolumeRemove(id string, query *types.VolumeRemoveQuery) error {
+	return app.client.VolumeRemove(context.Background(), id, query.Force)
+}
+
+// VolumesPrune removes volumes no container uses, matching the label filters
+// in params. Like the daemon, only anonymous volumes are considered unless
+// all is set. The daemon does not filter volumes by age, so until is
+// rejected.
+func (app *App) VolumesPrune(params *types.VolumePruneParams) (*types.PruneReport, error) {
+	if params.Until != "" {
+		return nil, fmt.Errorf("until: volumes cannot be pruned by age")
+	}
+	args, _, err := pruneFilters(&params.PruneParams)
+	if err != nil {
+		return nil, err
+	}
+	if params.All {
+		args.Add("all", "true")
+	}
+	if !params.DryRun {
+		report, err := app.client.VolumesPrune(context.Background(), args)
+		if err != nil {
+			return nil, err
+		}
+		deleted := report.VolumesDeleted
+		if deleted == nil {
+			deleted = make([]string, 0)
+		}
+		return &types.PruneReport{
+			Deleted:        deleted,
+			SpaceReclaimed: report.SpaceReclaimed,
+		}, nil
+	}
+
+	// only disk usage has the reference counts and sizes
+	du, err := app.client.DiskUsage(context.Background(), dockertypes.DiskUsageOptions{
+		Types: []dockertypes.DiskUsageObject{dockertypes.VolumeObject},
+	})
+	if err != nil {
+		return nil, err
+	}
+	report := &types.PruneReport{
+		DryRun:  true,
+		Deleted: make([]string, 0),
+	}
+	for _, v := range du.Volumes {
+		if v.UsageData == nil || v.UsageData.RefCount > 0 {
+			continue
+		}
+		if _, anonymous := v.Labels[anonymousVolumeLabel]; !params.All && !anonymous {
+			continue
+		}
+		if !matchLabels(v.Labels, params.Labels, params.ExcludeLabels) {
+			continue
+		}
+		report.Deleted = append(report.Deleted, v.Name)
+		if v.UsageData.Size > 0 {
+			report.SpaceReclaimed += uint64(v.UsageData.Size)
+		}
+	}
+	return report, nil
+}
+
+// volumeContainers maps volume names to the containers mounting them
+func (app *App) volumeContainers() (map[string][]types.VolumeContainer, error) {
+	containers, err := app.client.ContainerList(context.Background(), container.ListOptions{All: true})
+	if err != nil {
+		return nil, err
+	}
+	users := map[string][]types.VolumeContainer{}
+	for _, c := range containers {
+		if isVolumeHelper(&c) {
+			continue
+		}
+		for _, m := range c.Mounts {
+			if m.Type != mount.TypeVolume {
+				continue
+			}
+			users[m.Name] = append(users[m.Name], types.VolumeContainer{
+				ID:          c.ID,
+				Name:        containerName(&c),
+				Destination: m.Destination,
+				ReadOnly:    !m.RW,
+			})
+		}
+	}
+	return users, nil
+}
@@ -10,6 +10,7 @@ require (
 	github.com/googollee/go-engine.io v1.4.2
 	github.com/googollee/go-socket.io v1.7.0
 	github.com/gorilla/websocket v1.5.3
+	github.com/moby/patternmatcher v0.6.0
 	github.com/zishang520/engine.io/v2 v2.2.3
 	github.com/zishang520/socket.io v1.3.2
 	gorm.io/driver/sqlite v1.5.6
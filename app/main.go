@@ -1,9 +1,13 @@
 package main
 
 import (
+	"archive/tar"
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,16 +15,100 @@ import (
 	"reactor/app"
 	"reactor/models"
 	"reactor/types"
+	"reactor/utils"
+	"strings"
+	"sync"
 	"syscall"
 
 	ginGzip "github.com/gin-contrib/gzip"
 
+	"github.com/docker/docker/api/types/container"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	engineiotypes "github.com/zishang520/engine.io/types"
 	"github.com/zishang520/socket.io/socket"
 )
 
+// isArchiveName reports whether an uploaded file name looks like a tar archive
+// that the daemon can extract as is.
+func isArchiveName(name string) bool {
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tar.xz"} {
+		if strings.HasSuffix(strings.ToLower(name), ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestUpload returns the uploaded content of a request, either the raw
+// request body or the "file" field of a multipart form. The file header is
+// only set for multipart uploads.
+func requestUpload(ctx *gin.Context) (io.ReadCloser, *multipart.FileHeader, error) {
+	if !strings.HasPrefix(ctx.ContentType(), "multipart/") {
+		return ctx.Request.Body, nil, nil
+	}
+	f, err := ctx.FormFile("file")
+	if err != nil {
+		return nil, nil, err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return rc, f, nil
+}
+
+// requestArchive returns the uploaded tarball, either the raw request body or
+// the "file" field of a multipart form.
+func requestArchive(ctx *gin.Context) (io.ReadCloser, error) {
+	rc, _, err := requestUpload(ctx)
+	return rc, err
+}
+
+// uploadedArchive returns the tar stream of a put_archive request like
+// requestArchive, except that a single uploaded file is wrapped in a tar
+// unless it already is one.
+func uploadedArchive(ctx *gin.Context) (io.ReadCloser, error) {
+	rc, f, err := requestUpload(ctx)
+	if err != nil || f == nil {
+		return rc, err
+	}
+	if ctx.PostForm("archive") == "true" || isArchiveName(f.Filename) {
+		return rc, nil
+	}
+	// closing the archive also closes the multipart file
+	content, err := utils.SingleFileArchive(f.Filename, f.Size, 0644, rc)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return content, nil
+}
+
+// sendArchive writes the response of a get_archive request: the tar stream as
+// is, or with query.Raw the content of the single regular file in it.
+func sendArchive(ctx *gin.Context, rc io.Reader, stat *container.PathStat, query *types.ContainerArchiveQuery) {
+	if query.Raw {
+		if !stat.Mode.IsRegular() {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s is not a regular file", query.Path)})
+			return
+		}
+		tr := tar.NewReader(rc)
+		hdr, err := utils.FirstFileInArchive(tr)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.DataFromReader(http.StatusOK, hdr.Size, "application/octet-stream", tr, map[string]string{
+			"Content-Disposition": fmt.Sprintf("attachment; filename=%q", stat.Name),
+		})
+		return
+	}
+	ctx.DataFromReader(http.StatusOK, -1, "application/x-tar", rc, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", stat.Name+".tar"),
+	})
+}
+
 func setupSocketServer(app *app.App) *socket.Server {
 	app.Subscribers = map[string]*types.Subscriber{}
 	ss := socket.NewServer(nil, nil)
@@ -124,6 +212,136 @@ func setupSocketServer(app *app.App) *socket.Server {
 			}
 			client.Emit("subbed", id, client.Id())
 		})
+		attached := map[string]bool{}
+		var mu sync.Mutex
+		client.On("attach", func(args ...any) {
+			var params types.ContainerAttachParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("attach_error", "", err.Error())
+				return
+			}
+			app.Subscribers[params.ID] = &types.Subscriber{
+				ID:         string(client.Id()),
+				Connection: client,
+			}
+			err = app.ContainerAttach(client, &params)
+			if err != nil {
+				client.Emit("attach_error", params.ID, err.Error())
+				return
+			}
+			mu.Lock()
+			attached[params.ID] = true
+			mu.Unlock()
+			fmt.Println("[attach]:", params.ID)
+			client.Emit("attached", params.ID)
+		})
+		client.On("attach_input", func(args ...any) {
+			var params types.ContainerAttachInputParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("attach_error", "", err.Error())
+				return
+			}
+			// only the socket that attached may write to the session
+			mu.Lock()
+			owned := attached[params.ID]
+			mu.Unlock()
+			if !owned {
+				client.Emit("attach_error", params.ID, "container is not attached")
+				return
+			}
+			err = app.ContainerAttachInput(&params)
+			if err != nil {
+				client.Emit("attach_error", params.ID, err.Error())
+			}
+		})
+		client.On("detach", func(args ...any) {
+			var params types.ContainerAttachInputParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("attach_error", "", err.Error())
+				return
+			}
+			mu.Lock()
+			owned := attached[params.ID]
+			delete(attached, params.ID)
+			mu.Unlock()
+			if !owned {
+				client.Emit("attach_error", params.ID, "container is not attached")
+				return
+			}
+			app.ContainerDetach(params.ID)
+		})
+		// streams are compared by pointer, a re-subscribe replaces the entry
+		type statsStream struct {
+			stop context.CancelFunc
+		}
+		statsStreams := map[string]*statsStream{}
+		client.On("stats_subscribe", func(args ...any) {
+			var params types.SubscribeParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil || params.Id == "" {
+				client.Emit("stats_error", "", "id is required")
+				return
+			}
+			id := params.Id
+			streamCtx, cancel := context.WithCancel(context.Background())
+			stream := &statsStream{stop: cancel}
+			mu.Lock()
+			if old := statsStreams[id]; old != nil {
+				old.stop()
+			}
+			statsStreams[id] = stream
+			mu.Unlock()
+			go func() {
+				defer cancel()
+				err := app.ContainerStatsStream(streamCtx, id, func(stats *types.ContainerStats) {
+					client.Emit("stats", id, stats)
+				})
+				mu.Lock()
+				current := statsStreams[id]
+				if current == stream {
+					delete(statsStreams, id)
+				}
+				mu.Unlock()
+				// the stream that replaced this one reports for the id from now on
+				if current != nil && current != stream {
+					return
+				}
+				if err != nil && streamCtx.Err() == nil {
+					client.Emit("stats_error", id, err.Error())
+				}
+				client.Emit("stats_end", id)
+			}()
+		})
+		client.On("stats_unsubscribe", func(args ...any) {
+			var params types.SubscribeParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("stats_error", "", err.Error())
+				return
+			}
+			mu.Lock()
+			if stream := statsStreams[params.Id]; stream != nil {
+				stream.stop()
+			}
+			delete(statsStreams, params.Id)
+			mu.Unlock()
+		})
+		client.On("disconnect", func(args ...any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for id := range attached {
+				fmt.Println("[detach]:", id)
+				app.ContainerDetach(id)
+			}
+			clear(attached)
+			for _, stream := range statsStreams {
+				stream.stop()
+			}
+			clear(statsStreams)
+		})
 	})
 	ss.Of("/image", func(clients ...any) {
 		client := clients[0].(*socket.Socket)
@@ -170,6 +388,91 @@ func setupSocketServer(app *app.App) *socket.Server {
 			client.Emit("subbed", id, client.Id())
 		})
 	})
+	ss.Of("/terminal", func(clients ...any) {
+		client := clients[0].(*socket.Socket)
+		sessions := map[string]bool{}
+		var mu sync.Mutex
+		client.On("open", func(args ...any) {
+			var params types.ContainerTerminalParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("terminal_error", "", err.Error())
+				return
+			}
+			execID, err := app.ContainerTerminal(client, &params)
+			if err != nil {
+				client.Emit("terminal_error", "", err.Error())
+				return
+			}
+			mu.Lock()
+			sessions[execID] = true
+			mu.Unlock()
+			fmt.Println("[terminal#open]:", params.ID, execID)
+			client.Emit("opened", params.ID, execID)
+		})
+		client.On("input", func(args ...any) {
+			var params types.ContainerTerminalInputParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("terminal_error", "", err.Error())
+				return
+			}
+			mu.Lock()
+			owned := sessions[params.ExecID]
+			mu.Unlock()
+			if !owned {
+				client.Emit("terminal_error", params.ExecID, "no such terminal session")
+				return
+			}
+			err = app.TerminalInput(&params)
+			if err != nil {
+				client.Emit("terminal_error", params.ExecID, err.Error())
+			}
+		})
+		client.On("resize", func(args ...any) {
+			var params types.ContainerTerminalResizeParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("terminal_error", "", err.Error())
+				return
+			}
+			mu.Lock()
+			owned := sessions[params.ExecID]
+			mu.Unlock()
+			if !owned {
+				client.Emit("terminal_error", params.ExecID, "no such terminal session")
+				return
+			}
+			err = app.TerminalResize(&params)
+			if err != nil {
+				client.Emit("terminal_error", params.ExecID, err.Error())
+			}
+		})
+		client.On("close", func(args ...any) {
+			var params types.ContainerTerminalInputParams
+			err := utils.DecodeRecord(args[0], &params)
+			if err != nil {
+				client.Emit("terminal_error", "", err.Error())
+				return
+			}
+			mu.Lock()
+			owned := sessions[params.ExecID]
+			delete(sessions, params.ExecID)
+			mu.Unlock()
+			if owned {
+				app.CloseTerminal(params.ExecID)
+			}
+		})
+		client.On("disconnect", func(args ...any) {
+			mu.Lock()
+			defer mu.Unlock()
+			for execID := range sessions {
+				fmt.Println("[terminal#close]:", execID)
+				app.CloseTerminal(execID)
+			}
+			clear(sessions)
+		})
+	})
 	app.SocketServer = ss
 	return ss
 }
@@ -296,6 +599,82 @@ func main() {
 			ctx.JSON(http.StatusOK, gin.H{"ok": statusOk, "error": errStr})
 		})
 
+	r.
+		GET("/registries", func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{"list": app.ListRegistryCredentials()})
+		}).
+		POST("/registries", func(ctx *gin.Context) {
+			var body models.RegistryCredential
+			err := ctx.ShouldBindJSON(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			err = app.SaveRegistryCredential(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"data": body})
+		}).
+		GET("/registries/:id", func(ctx *gin.Context) {
+			var params types.CommonRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			data, ok := app.GetRegistryCredential(params.ID)
+			if !ok {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "credential not found"})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"data": data})
+		}).
+		PUT("/registries/:id", func(ctx *gin.Context) {
+			var params types.CommonRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var body models.RegistryCredential
+			err = ctx.ShouldBindJSON(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			data, err := app.UpdateRegistryCredential(params.ID, &body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"data": data})
+		}).
+		DELETE("/registries/:id", func(ctx *gin.Context) {
+			var params types.CommonRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if !app.DeleteRegistryCredential(params.ID) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "credential not found"})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		POST("/registries/:id/test", func(ctx *gin.Context) {
+			var params types.CommonRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			statusOk, errStr := app.TestRegistryCredential(params.ID)
+			ctx.JSON(http.StatusOK, gin.H{"ok": statusOk, "error": errStr})
+		})
+
 	r.
 		GET("/containers", func(ctx *gin.Context) {
 			var query types.ContainerListQueryParams
@@ -323,7 +702,11 @@ func main() {
 
 	r.POST("/containers/create", func(ctx *gin.Context) {
 		var params types.ContainerCreateParams
-		ctx.ShouldBindJSON(&params)
+		err := ctx.ShouldBindJSON(&params)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		r, e := app.ContainerCreate(&params)
 		if e != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"msg": e.Error()})
@@ -331,7 +714,20 @@ func main() {
 		}
 		ctx.JSON(http.StatusOK, r)
 	}).
-		DELETE("/containers/prune", func(ctx *gin.Context) {})
+		DELETE("/containers/prune", func(ctx *gin.Context) {
+			var params types.PruneParams
+			err := ctx.ShouldBindJSON(&params)
+			if err != nil && err != io.EOF {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			report, err := app.ContainersPrune(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, report)
+		})
 
 	r.
 		POST("/containers/run", func(ctx *gin.Context) {
@@ -508,223 +904,571 @@ func main() {
 			}
 			ctx.JSON(http.StatusOK, gin.H{"stats": stats})
 		}).
-		GET("/container/:id/top", func(ctx *gin.Context) {
-			var params types.ContainerTopParams
+		GET("/container/:id/stats/history", func(ctx *gin.Context) {
+			var params types.ContainerStatsParams
 			err := ctx.ShouldBindUri(&params)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			top, err := app.ContainerTop(&params)
+			var query types.ContainerStatsHistoryQuery
+			err = ctx.ShouldBindQuery(&query)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			// ctx.JSON(http.StatusOK, gin.H{"top": top})
-			ctx.JSON(http.StatusOK, gin.H{"top": types.Record{"titles": top.Titles, "processes": top.Processes}})
-		}).
-		POST("/container/:id/put_archive", func(ctx *gin.Context) {}).
-		GET("/container/:id/get_archive", func(ctx *gin.Context) {}).
-		POST("/container/:id/export", func(ctx *gin.Context) {
-			var params types.ContainerExportParams
-			err := ctx.ShouldBindUri(&params)
+			history, err := app.ContainerStatsHistory(&params, &query)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			chb := make(chan []byte)
-			go func() {
-				bytes, err := app.ContainerExport(&params)
-				if err != nil {
-					ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-					return
-				}
-				chb <- bytes
-			}()
-			bytes := <-chb
-			_, _ = ctx.Writer.Write(bytes)
-			ctx.Status(http.StatusOK)
-			/* ctx.Stream(func(w io.Writer) bool {
-				_, e := w.Write(bytes)
-				return e == nil
-			})
-			ctx.JSON(http.StatusOK, gin.H{"error": "ok"}) */
+			ctx.JSON(http.StatusOK, gin.H{"history": history})
 		}).
-		GET("/container/:id/files", func(ctx *gin.Context) {}).
-		GET("/container/:id/logs", func(ctx *gin.Context) {
-			var params types.ContainerLogsParams
+		GET("/container/:id/top", func(ctx *gin.Context) {
+			var params types.ContainerTopParams
 			err := ctx.ShouldBindUri(&params)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			var query types.ContainerLogsQuery
-			ctx.BindQuery(&query)
-			logs, err := app.ContainerLogs(&params, &query)
+			top, err := app.ContainerTop(&params)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			ctx.JSON(http.StatusOK, gin.H{"logs": logs})
+			// ctx.JSON(http.StatusOK, gin.H{"top": top})
+			ctx.JSON(http.StatusOK, gin.H{"top": types.Record{"titles": top.Titles, "processes": top.Processes}})
 		}).
-		POST("/container/:id/exec", func(ctx *gin.Context) {
-			var params types.ContainerExecParams
+		POST("/container/:id/attach", func(ctx *gin.Context) {
+			var params types.ContainerAttachParams
 			err := ctx.ShouldBindUri(&params)
 			if err != nil {
-				fmt.Println("cannot bind params:", err.Error())
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			var body types.ContainerExecBody
-			err = ctx.BindJSON(&body)
-			if err != nil {
-				fmt.Println("cannot bind body:", err.Error())
+			err = ctx.ShouldBindJSON(&params)
+			if err != nil && err != io.EOF {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			fmt.Println("[exec#params]:", params, body)
-			err = app.ContainerExec(&params, &body)
+			ok, sub := app.GetSub(params.ID)
+			if !ok {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "subscribe to the container on the /container namespace before attaching"})
+				return
+			}
+			err = app.ContainerAttach(sub.Connection, &params)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			ctx.Status(http.StatusOK)
+			ctx.JSON(http.StatusOK, gin.H{"id": params.ID, "subscriber": sub.ID})
 		}).
-		PATCH("/container/:id/rename", func(ctx *gin.Context) {
+		POST("/container/:id/detach", func(ctx *gin.Context) {
 			var params types.ContainerRequestParams
 			err := ctx.ShouldBindUri(&params)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			var body types.ContainerRenameParams
-			err = ctx.ShouldBindJSON(&body)
+			if !app.ContainerDetach(params.ID) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "container is not attached"})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		POST("/container/:id/put_archive", func(ctx *gin.Context) {
+			var params types.ContainerRequestParams
+			err := ctx.ShouldBindUri(&params)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			err = app.ContainerRename(&params, &body)
+			var query types.ContainerArchiveQuery
+			err = ctx.ShouldBindQuery(&query)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			ctx.Status(http.StatusOK)
-		}).
-		DELETE("/container/:id", func(ctx *gin.Context) {
-			var params types.ContainerRemoveParams
-			err := ctx.ShouldBindJSON(&params)
+			content, err := uploadedArchive(ctx)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			err = app.ContainerRemove(&params)
+			defer content.Close()
+			err = app.ContainerPutArchive(&params, &query, content)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			ctx.Status(http.StatusOK)
+			ctx.JSON(http.StatusOK, gin.H{"path": query.Path})
 		}).
-		POST("/images/create", func(ctx *gin.Context) {}).
-		POST("/images/pull", func(ctx *gin.Context) {
-			var params types.ImagePullParams
-			err := ctx.ShouldBindJSON(&params)
+		GET("/container/:id/get_archive", func(ctx *gin.Context) {
+			var params types.ContainerRequestParams
+			err := ctx.ShouldBindUri(&params)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			logs, err := app.ImagePull(&params)
+			var query types.ContainerArchiveQuery
+			err = ctx.ShouldBindQuery(&query)
 			if err != nil {
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			var progress types.ImagePullProgress
-			fmt.Println("[logs]:", logs)
-			json.Unmarshal([]byte(logs), &progress)
-			fmt.Println("[progress]:", progress)
-			ctx.JSON(http.StatusOK, gin.H{"logs": logs, "status": "ok"})
-		}).
-		POST("/images/build", func(ctx *gin.Context) {
-			ct := ctx.GetHeader("Content-Type")
-			enc := ctx.GetHeader("Accept-Encoding")
-			fmt.Println("[encoding]: ", enc, ct)
-			f, _ := ctx.FormFile("file")
-			tag := ctx.PostForm("tag")
-			fmt.Println("building image with tag:", tag)
-			// ctx.SaveUploadedFile(f, f.Filename)
-			_, err := os.Stat(".tmp")
+			rc, stat, err := app.ContainerGetArchive(&params, &query)
 			if err != nil {
-				fmt.Println("stat error: ", err.Error())
-			}
-			if os.IsNotExist(err) {
-				os.Mkdir(".tmp", os.ModePerm)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
 			}
-			tmpdir, err := os.MkdirTemp(".tmp", "build")
+			defer rc.Close()
+			sendArchive(ctx, rc, stat, &query)
+		}).
+		POST("/container/:id/export", func(ctx *gin.Context) {
+			var params types.ContainerExportParams
+			err := ctx.ShouldBindUri(&params)
 			if err != nil {
-				fmt.Println("error processing file:", err.Error())
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			_, err = os.Stat(tmpdir)
+			chb := make(chan []byte)
+			go func() {
+				bytes, err := app.ContainerExport(&params)
+				if err != nil {
+					ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				chb <- bytes
+			}()
+			bytes := <-chb
+			_, _ = ctx.Writer.Write(bytes)
+			ctx.Status(http.StatusOK)
+			/* ctx.Stream(func(w io.Writer) bool {
+				_, e := w.Write(bytes)
+				return e == nil
+			})
+			ctx.JSON(http.StatusOK, gin.H{"error": "ok"}) */
+		}).
+		GET("/container/:id/files", func(ctx *gin.Context) {
+			var params types.ContainerRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ContainerFilesQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			listing, err := app.ContainerListFiles(&params, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"data": listing})
+		}).
+		GET("/container/:id/files/preview", func(ctx *gin.Context) {
+			var params types.ContainerRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ContainerFilesQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			preview, err := app.ContainerPreviewFile(&params, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"data": preview})
+		}).
+		GET("/container/:id/logs", func(ctx *gin.Context) {
+			var params types.ContainerLogsParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ContainerLogsQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if !query.Follow {
+				logs, err := app.ContainerLogs(&params, &query)
+				if err != nil {
+					ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				ctx.JSON(http.StatusOK, gin.H{"logs": logs})
+				return
+			}
+			frames := make(chan *types.StreamFrame)
+			err = app.ContainerLogsFollow(ctx.Request.Context(), &params, &query, frames)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Header("Cache-Control", "no-cache")
+			ctx.Header("Connection", "keep-alive")
+			ctx.Stream(func(w io.Writer) bool {
+				frame, ok := <-frames
+				if !ok {
+					ctx.SSEvent("end", params.ID)
+					return false
+				}
+				ctx.SSEvent(frame.Stream, frame.Data)
+				return true
+			})
+		}).
+		POST("/container/:id/exec", func(ctx *gin.Context) {
+			var params types.ContainerExecParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				fmt.Println("cannot bind params:", err.Error())
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var body types.ContainerExecBody
+			err = ctx.BindJSON(&body)
+			if err != nil {
+				fmt.Println("cannot bind body:", err.Error())
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			fmt.Println("[exec#params]:", params, body)
+			err = app.ContainerExec(&params, &body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		POST("/container/:id/commit", func(ctx *gin.Context) {
+			var params types.ContainerRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var body types.ContainerCommitParams
+			err = ctx.ShouldBindJSON(&body)
+			if err != nil && err != io.EOF {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			id, err := app.ContainerCommit(&params, &body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"id": id})
+		}).
+		PATCH("/container/:id/rename", func(ctx *gin.Context) {
+			var params types.ContainerRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var body types.ContainerRenameParams
+			err = ctx.ShouldBindJSON(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			err = app.ContainerRename(&params, &body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		DELETE("/container/:id", func(ctx *gin.Context) {
+			var params types.ContainerRemoveParams
+			err := ctx.ShouldBindJSON(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			err = app.ContainerRemove(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		POST("/images/create", func(ctx *gin.Context) {
+			// images are created by pulling, building or importing them
+			ctx.JSON(http.StatusNotImplemented, gin.H{"error": "use /images/pull, /images/build or /images/import"})
+		}).
+		POST("/images/pull", func(ctx *gin.Context) {
+			var params types.ImagePullParams
+			err := ctx.ShouldBindJSON(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			job, err := app.ImagePull(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusAccepted, gin.H{"job": job})
+		}).
+		POST("/images/push", func(ctx *gin.Context) {
+			var params types.ImagePushParams
+			err := ctx.ShouldBindJSON(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			job, err := app.ImagePush(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusAccepted, gin.H{"job": job})
+		}).
+		POST("/images/build", func(ctx *gin.Context) {
+			var params types.ImageBuildParams
+			err := ctx.ShouldBind(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			fmt.Println("building image with tags:", params.Tag, params.Tags)
+			f, err := ctx.FormFile("file")
+			if err == http.ErrMissingFile || err == http.ErrNotMultipart {
+				// no prepared context, assemble one from the inline Dockerfile and uploads
+				var files []*multipart.FileHeader
+				if err == http.ErrMissingFile {
+					form, err := ctx.MultipartForm()
+					if err != nil {
+						ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+						return
+					}
+					files = form.File["files"]
+				}
+				buildContext, err := app.BuildContextFromFiles(&params, files)
+				if err != nil {
+					ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				job, err := app.ImageBuild(buildContext, &params)
+				if err != nil {
+					ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+				ctx.JSON(http.StatusAccepted, gin.H{"job": job})
+				return
+			}
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			_, err = os.Stat(".tmp")
+			if os.IsNotExist(err) {
+				os.Mkdir(".tmp", os.ModePerm)
+			}
+			tmpdir, err := os.MkdirTemp(".tmp", "build")
+			if err != nil {
+				fmt.Println("error processing file:", err.Error())
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			savePath := path.Join(tmpdir, f.Filename)
+			err = ctx.SaveUploadedFile(f, savePath)
+			if err != nil {
+				os.RemoveAll(tmpdir)
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			// reject anything that is not gzip before handing it to the daemon
+			r, err := os.Open(savePath)
+			if err == nil {
+				var unc *gzip.Reader
+				unc, err = gzip.NewReader(r)
+				if err == nil {
+					unc.Close()
+					_, err = r.Seek(0, io.SeekStart)
+				}
+			}
+			if err != nil {
+				if r != nil {
+					r.Close()
+				}
+				os.RemoveAll(tmpdir)
+				fmt.Println("error processing image build:", err.Error())
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			job, err := app.ImageBuild(utils.RemoveOnClose(r, tmpdir), &params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusAccepted, gin.H{"job": job})
+		}).
+		PUT("/images/build/:id/cancel", func(ctx *gin.Context) {
+			var params types.JobRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			job, ok := app.GetJob(params.ID)
+			if !ok || job.Kind != "build" {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "build not found"})
+				return
+			}
+			err = app.CancelJob(params.ID)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		DELETE("/images/prune", func(ctx *gin.Context) {
+			var params types.ImagePruneParams
+			err := ctx.ShouldBindJSON(&params)
+			if err != nil && err != io.EOF {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			report, err := app.ImagesPrune(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, report)
+		}).
+		GET("/images/save", func(ctx *gin.Context) {
+			var query types.ImageSaveQuery
+			err := ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			rc, err := app.ImageSave(ctx.Request.Context(), &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer rc.Close()
+			ctx.DataFromReader(http.StatusOK, -1, "application/x-tar", rc, map[string]string{
+				"Content-Disposition": `attachment; filename="images.tar"`,
+			})
+		}).
+		POST("/images/load", func(ctx *gin.Context) {
+			content, err := requestArchive(ctx)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer content.Close()
+			loaded, err := app.ImageLoad(ctx.Request.Context(), content)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "loaded": loaded})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"loaded": loaded})
+		}).
+		POST("/images/import", func(ctx *gin.Context) {
+			var query types.ImageImportQuery
+			err := ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			content, err := requestArchive(ctx)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer content.Close()
+			id, ref, err := app.ImageImport(ctx.Request.Context(), &query, content)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"id": id, "ref": ref})
+		}).
+		GET("/images/diff", func(ctx *gin.Context) {
+			var query types.ImageDiffQuery
+			err := ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			diff, err := app.ImageDiff(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, diff)
+		}).
+		POST("/images/untag", func(ctx *gin.Context) {
+			var body types.ImageUntagParams
+			err := ctx.ShouldBindJSON(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			report, err := app.ImageUntag(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, report)
+		}).
+		POST("/image/:id/tag", func(ctx *gin.Context) {
+			var params types.ImageRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var body types.ImageTagParams
+			err = ctx.ShouldBindJSON(&body)
 			if err != nil {
-				fmt.Println("error stat: ", err.Error(), os.IsNotExist(err))
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
 			}
-			savePath := path.Join(tmpdir, f.Filename)
-			ctx.SaveUploadedFile(f, savePath)
-
-			ch := make(chan bool)
-			che := make(chan error)
-			done := make(chan bool)
-			go func() {
-				r, err := os.Open(savePath)
-				if err != nil {
-					fmt.Println("error reading file:", err)
-					che <- err
-					return
-				}
-				unc, err := gzip.NewReader(r)
-				if err != nil {
-					che <- err
-					return
-				}
-
-				defer unc.Close()
-
-				sp := savePath
-				fmt.Println("[build#result]: ", sp)
-				if savePath != "" {
-					e := app.ImageBuild(savePath, tag)
-					if e != nil {
-						fmt.Println("build error: ", e.Error())
-						che <- e
-						done <- true
-						return
-					}
-					ch <- e == nil
-					os.RemoveAll(tmpdir)
-				}
-				fmt.Println("exiting goroutine")
-				che <- nil
-				done <- true
-			}()
-			ok := <-ch
-			err = <-che
-			d := <-done
-			if d {
-				fmt.Println("closing channels")
-				close(ch)
-				close(che)
+			ref, err := app.ImageTag(params.ID, &body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
 			}
+			ctx.JSON(http.StatusOK, gin.H{"ref": ref})
+		}).
+		DELETE("/image/:id", func(ctx *gin.Context) {
+			var params types.ImageRequestParams
+			err := ctx.ShouldBindUri(&params)
 			if err != nil {
-				fmt.Println("error processing image build:", err.Error())
 				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			fmt.Println("[ok]: ", ok)
-
-			ctx.JSON(http.StatusOK, gin.H{"filename": savePath})
+			var query types.ImageRemoveQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			report, err := app.ImageRemove(params.ID, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, report)
 		}).
-		PUT("/images/build/:id/cancel", func(ctx *gin.Context) {}).
-		DELETE("/images/prune", func(ctx *gin.Context) {}).
 		GET("/image/:id/inspect", func(ctx *gin.Context) {
 			var params types.ImageRequestParams
 			err := ctx.ShouldBindUri(&params)
@@ -742,6 +1486,82 @@ func main() {
 
 			j, _ := json.Marshal(inspectJson)
 			ctx.String(http.StatusOK, string(j))
+		}).
+		GET("/image/:id/history", func(ctx *gin.Context) {
+			var params types.ImageRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			history, err := app.ImageHistory(params.ID)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, history)
+		}).
+		GET("/image/:id/layers", func(ctx *gin.Context) {
+			var params types.ImageRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			report, err := app.ImageLayers(params.ID)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, report)
+		}).
+		GET("/image/:id/layers/:index", func(ctx *gin.Context) {
+			var params types.ImageLayerRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ImageLayerFilesQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			files, err := app.ImageLayerFiles(&params, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, files)
+		})
+
+	r.GET("/topology", func(ctx *gin.Context) {
+		topology, err := app.Topology()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, topology)
+	})
+
+	r.
+		GET("/jobs", func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{"list": app.ListJobs()})
+		}).
+		GET("/jobs/:id", func(ctx *gin.Context) {
+			var params types.JobRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			job, ok := app.GetJob(params.ID)
+			if !ok {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"job": job})
 		})
 
 	r.GET("/volume/:id/inspect", func(ctx *gin.Context) {
@@ -763,6 +1583,143 @@ func main() {
 		ctx.String(http.StatusOK, string(j))
 	})
 
+	r.
+		POST("/volumes", func(ctx *gin.Context) {
+			var body types.VolumeCreateParams
+			err := ctx.ShouldBindJSON(&body)
+			if err != nil && err != io.EOF {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			v, err := app.VolumeCreate(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, v)
+		}).
+		DELETE("/volumes/prune", func(ctx *gin.Context) {
+			var params types.VolumePruneParams
+			err := ctx.ShouldBindJSON(&params)
+			if err != nil && err != io.EOF {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			report, err := app.VolumesPrune(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, report)
+		}).
+		DELETE("/volume/:id", func(ctx *gin.Context) {
+			var params types.VolumeRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.VolumeRemoveQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			err = app.VolumeRemove(params.ID, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		GET("/volume/:id/files", func(ctx *gin.Context) {
+			var params types.VolumeRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ContainerFilesQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			listing, err := app.VolumeListFiles(&params, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"data": listing})
+		}).
+		GET("/volume/:id/files/preview", func(ctx *gin.Context) {
+			var params types.VolumeRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ContainerFilesQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			preview, err := app.VolumePreviewFile(&params, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"data": preview})
+		}).
+		POST("/volume/:id/put_archive", func(ctx *gin.Context) {
+			var params types.VolumeRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ContainerArchiveQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			content, err := uploadedArchive(ctx)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer content.Close()
+			err = app.VolumePutArchive(&params, &query, content)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"path": query.Path})
+		}).
+		GET("/volume/:id/get_archive", func(ctx *gin.Context) {
+			var params types.VolumeRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var query types.ContainerArchiveQuery
+			err = ctx.ShouldBindQuery(&query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			rc, stat, err := app.VolumeGetArchive(&params, &query)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			defer rc.Close()
+			sendArchive(ctx, rc, stat, &query)
+		})
+
 	r.
 		GET("/network/:id/inspect", func(ctx *gin.Context) {
 			var params types.NetworkRequestParams
@@ -781,6 +1738,88 @@ func main() {
 
 			j, _ := json.Marshal(inspectJson)
 			ctx.String(http.StatusOK, string(j))
+		}).
+		POST("/networks", func(ctx *gin.Context) {
+			var body types.NetworkCreateParams
+			err := ctx.ShouldBindJSON(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			res, err := app.NetworkCreate(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{"id": res.ID, "warning": res.Warning})
+		}).
+		DELETE("/networks/prune", func(ctx *gin.Context) {
+			var params types.PruneParams
+			err := ctx.ShouldBindJSON(&params)
+			if err != nil && err != io.EOF {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			report, err := app.NetworksPrune(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.JSON(http.StatusOK, report)
+		}).
+		DELETE("/network/:id", func(ctx *gin.Context) {
+			var params types.NetworkRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			err = app.NetworkRemove(params.ID)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		POST("/network/:id/connect", func(ctx *gin.Context) {
+			var params types.NetworkRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var body types.NetworkConnectParams
+			err = ctx.ShouldBindJSON(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			err = app.NetworkConnect(params.ID, &body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
+		}).
+		POST("/network/:id/disconnect", func(ctx *gin.Context) {
+			var params types.NetworkRequestParams
+			err := ctx.ShouldBindUri(&params)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var body types.NetworkDisconnectParams
+			err = ctx.ShouldBindJSON(&body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			err = app.NetworkDisconnect(params.ID, &body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			ctx.Status(http.StatusOK)
 		})
 
 	go func() {
@@ -151,6 +151,6 @@ func setupDB() *gorm.DB {
 	if err != nil {
 		panic(fmt.Sprintf("Failed to initialize DB: %s", err))
 	}
-	db.AutoMigrate(&ConnectionConfig{})
+	db.AutoMigrate(&ConnectionConfig{}, &ContainerStatsSample{}, &RegistryCredential{})
 	return db
 }
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reactor/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RegistryCredential is a login for a registry host. Password is only ever
+// set on input; it is stored encrypted in EncryptedPassword and never
+// serialised back.
+type RegistryCredential struct {
+	ID                string     `gorm:"type:uuid;primarykey" json:"id"`
+	Registry          string     `gorm:"uniqueIndex" json:"registry"`
+	Username          string     `json:"username"`
+	Password          string     `gorm:"-" json:"password,omitempty"`
+	EncryptedPassword string     `json:"-"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `gorm:"index" json:"deleted_at"`
+}
+
+func (c *RegistryCredential) BeforeCreate(tx *gorm.DB) error {
+	c.ID = uuid.NewString()
+	return nil
+}
+
+// BeforeSave encrypts a newly set password, leaving the stored one in place
+// when none was given.
+func (c *RegistryCredential) BeforeSave(tx *gorm.DB) error {
+	if c.Password == "" {
+		return nil
+	}
+	key, err := utils.DefaultConfigurationManager().GetSecretKey()
+	if err != nil {
+		return err
+	}
+	c.EncryptedPassword, err = utils.Encrypt(key, c.Password)
+	if err != nil {
+		return err
+	}
+	c.Password = ""
+	return nil
+}
+
+// Secret decrypts the stored password
+func (c *RegistryCredential) Secret() (string, error) {
+	if c.EncryptedPassword == "" {
+		return "", nil
+	}
+	key, err := utils.DefaultConfigurationManager().GetSecretKey()
+	if err != nil {
+		return "", err
+	}
+	return utils.Decrypt(key, c.EncryptedPassword)
+}
+
+type RegistryManager struct {
+	db *gorm.DB
+}
+
+var registryManager *RegistryManager
+
+func DefaultRegistryManager() *RegistryManager {
+	if registryManager == nil {
+		registryManager = &RegistryManager{}
+	}
+	return registryManager
+}
+
+// InitDefaults shares the database opened by the connection manager, so it has
+// to be called after ConnectionManager.InitDefaults.
+func (r *RegistryManager) InitDefaults() {
+	r.db = DefaultConnectionManager().db
+}
+func (r *RegistryManager) ListCredentials() []RegistryCredential {
+	creds := make([]RegistryCredential, 0)
+	r.db.Order("registry").Find(&creds)
+	return creds
+}
+func (r *RegistryManager) GetCredential(id string) (*RegistryCredential, bool) {
+	// a zero ID leaves the query without conditions, matching any credential
+	if id == "" {
+		return nil, false
+	}
+	cred := RegistryCredential{ID: id}
+	ra := r.db.First(&cred)
+	return &cred, ra.RowsAffected == 1
+}
+
+// FindCredential returns the credential for a registry host, if one is saved
+func (r *RegistryManager) FindCredential(host string) (*RegistryCredential, bool) {
+	host = utils.RegistryHost(host)
+	// gorm drops zero fields from struct conditions, so an empty host would
+	// match any credential
+	if host == "" {
+		return nil, false
+	}
+	var cred RegistryCredential
+	ra := r.db.Where(&RegistryCredential{Registry: host}).Limit(1).Find(&cred)
+	return &cred, ra.RowsAffected == 1
+}
+func (r *RegistryManager) SaveCredential(c *RegistryCredential) error {
+	c.Registry = utils.RegistryHost(c.Registry)
+	if c.Registry == "" {
+		return errors.New("registry: is required")
+	}
+	if c.Username == "" {
+		return errors.New("username: is required")
+	}
+	if c.Password == "" {
+		return errors.New("password: is required")
+	}
+	if _, ok := r.FindCredential(c.Registry); ok {
+		return fmt.Errorf("registry: a credential for %s already exists", c.Registry)
+	}
+	return r.db.Create(c).Error
+}
+
+// UpdateCredential changes the registry, username or password of a saved
+// credential. Empty fields are left as they are.
+func (r *RegistryManager) UpdateCredential(id string, p *RegistryCredential) (*RegistryCredential, error) {
+	cred, ok := r.GetCredential(id)
+	if !ok {
+		return nil, errors.New("credential not found")
+	}
+	if p.Registry != "" {
+		host := utils.RegistryHost(p.Registry)
+		if host == "" {
+			return nil, fmt.Errorf("registry: %q is not a registry address", p.Registry)
+		}
+		if other, ok := r.FindCredential(host); ok && other.ID != id {
+			return nil, fmt.Errorf("registry: a credential for %s already exists", host)
+		}
+		cred.Registry = host
+	}
+	if p.Username != "" {
+		cred.Username = p.Username
+	}
+	cred.Password = p.Password
+	err := r.db.Save(cred).Error
+	if err != nil {
+		return nil, err
+	}
+	return cred, nil
+}
+func (r *RegistryManager) DeleteCredential(id string) int64 {
+	ra := r.db.Delete(&RegistryCredential{ID: id})
+	return ra.RowsAffected
+}
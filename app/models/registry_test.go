@@ -0,0 +1,118 @@
+package models
+
+import (
+	"os"
+	"reactor/utils"
+	"testing"
+)
+
+// memoryRegistryManager returns a manager with credentials for Docker Hub and
+// a local registry saved. Passwords are encrypted with a key in a temporary
+// config path.
+func memoryRegistryManager(t *testing.T) *RegistryManager {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := os.MkdirAll(utils.DefaultConfigurationManager().GetConfigPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	r := &RegistryManager{db: memoryDB(t, &RegistryCredential{})}
+	for _, c := range []*RegistryCredential{
+		{Registry: "https://index.docker.io/v1/", Username: "hub", Password: "hub-secret"},
+		{Registry: "localhost:5000", Username: "local", Password: "local-secret"},
+	} {
+		if err := r.SaveCredential(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestFindCredential(t *testing.T) {
+	r := memoryRegistryManager(t)
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{host: "", expected: ""},
+		{host: "/", expected: ""},
+		{host: "docker.io", expected: "hub"},
+		{host: "index.docker.io", expected: "hub"},
+		{host: "registry-1.docker.io", expected: "hub"},
+		{host: "https://index.docker.io/v1/", expected: "hub"},
+		{host: "localhost:5000", expected: "local"},
+		{host: "http://localhost:5000/v2/", expected: "local"},
+		{host: "localhost", expected: ""},
+		{host: "localhost:5001", expected: ""},
+		{host: "ghcr.io", expected: ""},
+	}
+	for _, tt := range tests {
+		cred, ok := r.FindCredential(tt.host)
+		if tt.expected == "" {
+			if ok {
+				t.Errorf("%q: unexpected credential %s", tt.host, cred.Registry)
+			}
+			continue
+		}
+		if !ok || cred.Username != tt.expected {
+			t.Errorf("%q: got %v %+v, want %s", tt.host, ok, cred, tt.expected)
+		}
+	}
+}
+
+func TestSaveCredential(t *testing.T) {
+	r := memoryRegistryManager(t)
+	cred, ok := r.FindCredential("localhost:5000")
+	if !ok {
+		t.Fatal("credential not saved")
+	}
+	if cred.Password != "" || cred.EncryptedPassword == "" || cred.EncryptedPassword == "local-secret" {
+		t.Errorf("password not encrypted: %+v", cred)
+	}
+	secret, err := cred.Secret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret != "local-secret" {
+		t.Errorf("got secret %q", secret)
+	}
+
+	tests := []struct {
+		name string
+		cred RegistryCredential
+	}{
+		{name: "no registry", cred: RegistryCredential{Username: "u", Password: "p"}},
+		{name: "no username", cred: RegistryCredential{Registry: "ghcr.io", Password: "p"}},
+		{name: "no password", cred: RegistryCredential{Registry: "ghcr.io", Username: "u"}},
+		{name: "duplicate host", cred: RegistryCredential{Registry: "registry-1.docker.io", Username: "u", Password: "p"}},
+	}
+	for _, tt := range tests {
+		if err := r.SaveCredential(&tt.cred); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestUpdateCredential(t *testing.T) {
+	r := memoryRegistryManager(t)
+	local, _ := r.FindCredential("localhost:5000")
+	if _, err := r.UpdateCredential(local.ID, &RegistryCredential{Registry: "docker.io"}); err == nil {
+		t.Error("moving onto another credential's host should fail")
+	}
+	if _, err := r.UpdateCredential(local.ID, &RegistryCredential{Registry: "https://"}); err == nil {
+		t.Error("an address without a host should fail")
+	}
+	if _, err := r.UpdateCredential("", &RegistryCredential{Username: "other"}); err == nil {
+		t.Error("an empty id should not match any credential")
+	}
+	updated, err := r.UpdateCredential(local.ID, &RegistryCredential{Registry: "http://LOCALHOST:5001/v2/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Registry != "localhost:5001" || updated.Username != "local" {
+		t.Errorf("unexpected update %+v", updated)
+	}
+	secret, err := updated.Secret()
+	if err != nil || secret != "local-secret" {
+		t.Errorf("password changed to %q, %v", secret, err)
+	}
+}
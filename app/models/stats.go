@@ -0,0 +1,224 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StatsResolutionRaw    = "raw"
+	StatsResolutionMinute = "1m"
+	StatsResolutionHour   = "1h"
+)
+
+// raw samples are rolled up into minutes after an hour, minutes into hours
+// after a day, and hourly aggregates are dropped after 30 days. Samples of a
+// removed container are kept for a day after its last sample so its history
+// can still be looked at.
+const (
+	statsRawRetention     = time.Hour
+	statsMinuteRetention  = 24 * time.Hour
+	statsHourRetention    = 30 * 24 * time.Hour
+	statsRemovedRetention = 24 * time.Hour
+)
+
+type ContainerStatsSample struct {
+	ID            uint      `gorm:"primarykey" json:"-"`
+	ContainerID   string    `gorm:"index:idx_stats_container_time,priority:1" json:"container_id"`
+	Resolution    string    `gorm:"index:idx_stats_container_time,priority:2" json:"resolution"`
+	Time          time.Time `gorm:"index:idx_stats_container_time,priority:3" json:"time"`
+	Samples       int       `json:"samples"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	MemoryUsage   uint64    `json:"memory_usage"`
+	MemoryLimit   uint64    `json:"memory_limit"`
+	MemoryPercent float64   `json:"memory_percent"`
+	NetworkRxRate float64   `json:"network_rx_rate"`
+	NetworkTxRate float64   `json:"network_tx_rate"`
+	BlockRead     uint64    `json:"block_read"`
+	BlockWrite    uint64    `json:"block_write"`
+	PIDs          uint64    `json:"pids"`
+}
+
+type StatsManager struct {
+	db *gorm.DB
+}
+
+var statsManager *StatsManager
+
+func DefaultStatsManager() *StatsManager {
+	if statsManager == nil {
+		statsManager = &StatsManager{}
+	}
+	return statsManager
+}
+
+// InitDefaults shares the database opened by the connection manager, so it has
+// to be called after ConnectionManager.InitDefaults.
+func (s *StatsManager) InitDefaults() {
+	s.db = DefaultConnectionManager().db
+}
+func (s *StatsManager) SaveSamples(samples []ContainerStatsSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	// sqlite compares times as text, so everything is stored in UTC
+	for i := range samples {
+		samples[i].Time = samples[i].Time.UTC()
+	}
+	return s.db.Create(&samples).Error
+}
+
+// Rollup folds raw samples older than an hour into 1-minute aggregates and
+// 1-minute aggregates older than a day into 1-hour aggregates, then drops
+// hourly aggregates past their retention.
+func (s *StatsManager) Rollup(now time.Time) error {
+	now = now.UTC()
+	err := s.rollup(StatsResolutionRaw, StatsResolutionMinute, time.Minute, now.Add(-statsRawRetention))
+	if err != nil {
+		return err
+	}
+	err = s.rollup(StatsResolutionMinute, StatsResolutionHour, time.Hour, now.Add(-statsMinuteRetention))
+	if err != nil {
+		return err
+	}
+	return s.db.
+		Where("resolution = ? AND time < ?", StatsResolutionHour, now.Add(-statsHourRetention)).
+		Delete(&ContainerStatsSample{}).Error
+}
+func (s *StatsManager) rollup(from string, to string, bucket time.Duration, olderThan time.Time) error {
+	// only complete buckets are rolled up so an aggregate is never written twice
+	cutoff := olderThan.UTC().Truncate(bucket)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var samples []ContainerStatsSample
+		err := tx.
+			Where("resolution = ? AND time < ?", from, cutoff).
+			Order("container_id, time").
+			Find(&samples).Error
+		if err != nil || len(samples) == 0 {
+			return err
+		}
+		aggregates := AggregateStatsSamples(samples, bucket, to)
+		err = tx.Create(&aggregates).Error
+		if err != nil {
+			return err
+		}
+		return tx.
+			Where("resolution = ? AND time < ?", from, cutoff).
+			Delete(&ContainerStatsSample{}).Error
+	})
+}
+
+// PruneRemoved drops the samples of containers that are not in existing and
+// were last sampled before the removed retention.
+func (s *StatsManager) PruneRemoved(existing []string, now time.Time) error {
+	stale := s.db.
+		Model(&ContainerStatsSample{}).
+		Select("container_id").
+		Group("container_id").
+		Having("MAX(time) < ?", now.UTC().Add(-statsRemovedRetention))
+	q := s.db.Where("container_id IN (?)", stale)
+	// an empty NOT IN list renders as NOT IN (NULL), which matches nothing
+	if len(existing) > 0 {
+		q = q.Where("container_id NOT IN ?", existing)
+	}
+	return q.Delete(&ContainerStatsSample{}).Error
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ContainerIDs returns the IDs of the containers with recorded samples that
+// start with prefix.
+func (s *StatsManager) ContainerIDs(prefix string) ([]string, error) {
+	ids := make([]string, 0)
+	err := s.db.
+		Model(&ContainerStatsSample{}).
+		Distinct("container_id").
+		Where(`container_id LIKE ? ESCAPE '\'`, likeEscaper.Replace(prefix)+"%").
+		Pluck("container_id", &ids).Error
+	return ids, err
+}
+
+// History returns the samples of a container between from and to at the given
+// resolution. Samples that have not been rolled up yet are aggregated on the
+// fly, so recent data is included at coarser resolutions as well.
+func (s *StatsManager) History(containerID string, from time.Time, to time.Time, resolution string) ([]ContainerStatsSample, error) {
+	resolutions := []string{StatsResolutionRaw}
+	var bucket time.Duration
+	switch resolution {
+	case StatsResolutionMinute:
+		resolutions = append(resolutions, StatsResolutionMinute)
+		bucket = time.Minute
+	case StatsResolutionHour:
+		resolutions = append(resolutions, StatsResolutionMinute, StatsResolutionHour)
+		bucket = time.Hour
+	}
+	var samples []ContainerStatsSample
+	err := s.db.
+		Where("container_id = ? AND resolution IN ? AND time >= ? AND time <= ?", containerID, resolutions, from.UTC(), to.UTC()).
+		Order("time").
+		Find(&samples).Error
+	if err != nil {
+		return nil, err
+	}
+	if bucket == 0 {
+		return samples, nil
+	}
+	return AggregateStatsSamples(samples, bucket, resolution), nil
+}
+
+// AggregateStatsSamples groups samples per container into buckets of the given
+// size. Gauges are averaged weighted by the number of raw samples behind each
+// row, while cumulative counters and limits keep their latest value.
+func AggregateStatsSamples(samples []ContainerStatsSample, bucket time.Duration, resolution string) []ContainerStatsSample {
+	type key struct {
+		containerID string
+		time        time.Time
+	}
+	groups := map[key]*ContainerStatsSample{}
+	latest := map[key]time.Time{}
+	for _, sample := range samples {
+		weight := sample.Samples
+		if weight < 1 {
+			weight = 1
+		}
+		k := key{containerID: sample.ContainerID, time: sample.Time.Truncate(bucket)}
+		agg := groups[k]
+		if agg == nil {
+			agg = &ContainerStatsSample{
+				ContainerID: sample.ContainerID,
+				Resolution:  resolution,
+				Time:        k.time,
+			}
+			groups[k] = agg
+		}
+		w := float64(weight)
+		total := float64(agg.Samples)
+		agg.CPUPercent = (agg.CPUPercent*total + sample.CPUPercent*w) / (total + w)
+		agg.MemoryPercent = (agg.MemoryPercent*total + sample.MemoryPercent*w) / (total + w)
+		agg.MemoryUsage = uint64((float64(agg.MemoryUsage)*total + float64(sample.MemoryUsage)*w) / (total + w))
+		agg.NetworkRxRate = (agg.NetworkRxRate*total + sample.NetworkRxRate*w) / (total + w)
+		agg.NetworkTxRate = (agg.NetworkTxRate*total + sample.NetworkTxRate*w) / (total + w)
+		agg.Samples += weight
+		if !sample.Time.Before(latest[k]) {
+			latest[k] = sample.Time
+			agg.MemoryLimit = sample.MemoryLimit
+			agg.BlockRead = sample.BlockRead
+			agg.BlockWrite = sample.BlockWrite
+			agg.PIDs = sample.PIDs
+		}
+	}
+	aggregates := make([]ContainerStatsSample, 0, len(groups))
+	for _, agg := range groups {
+		aggregates = append(aggregates, *agg)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		if aggregates[i].ContainerID != aggregates[j].ContainerID {
+			return aggregates[i].ContainerID < aggregates[j].ContainerID
+		}
+		return aggregates[i].Time.Before(aggregates[j].Time)
+	})
+	return aggregates
+}
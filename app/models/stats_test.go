@@ -0,0 +1,162 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// memoryDB opens a private in-memory database with the given models migrated
+func memoryDB(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection to :memory: opens a database of its own
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestAggregateStatsSamples(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		samples  []ContainerStatsSample
+		expected []ContainerStatsSample
+	}{
+		{
+			name: "raw samples are averaged",
+			samples: []ContainerStatsSample{
+				{ContainerID: "a", Time: base.Add(10 * time.Second), CPUPercent: 10, MemoryUsage: 100, PIDs: 3, BlockRead: 5},
+				{ContainerID: "a", Time: base.Add(40 * time.Second), CPUPercent: 30, MemoryUsage: 300, PIDs: 4, BlockRead: 9},
+			},
+			expected: []ContainerStatsSample{
+				{ContainerID: "a", Time: base, Samples: 2, CPUPercent: 20, MemoryUsage: 200, PIDs: 4, BlockRead: 9},
+			},
+		},
+		{
+			name: "aggregates are weighted by their samples",
+			samples: []ContainerStatsSample{
+				{ContainerID: "a", Time: base, Samples: 3, CPUPercent: 10, NetworkRxRate: 100},
+				{ContainerID: "a", Time: base.Add(30 * time.Second), Samples: 1, CPUPercent: 50, NetworkRxRate: 500},
+			},
+			expected: []ContainerStatsSample{
+				{ContainerID: "a", Time: base, Samples: 4, CPUPercent: 20, NetworkRxRate: 200},
+			},
+		},
+		{
+			name: "latest counters win regardless of order",
+			samples: []ContainerStatsSample{
+				{ContainerID: "a", Time: base.Add(50 * time.Second), MemoryLimit: 2048, BlockWrite: 70},
+				{ContainerID: "a", Time: base.Add(5 * time.Second), MemoryLimit: 1024, BlockWrite: 10},
+			},
+			expected: []ContainerStatsSample{
+				{ContainerID: "a", Time: base, Samples: 2, MemoryLimit: 2048, BlockWrite: 70},
+			},
+		},
+		{
+			name: "grouped per container and bucket",
+			samples: []ContainerStatsSample{
+				{ContainerID: "b", Time: base.Add(time.Minute), CPUPercent: 4},
+				{ContainerID: "a", Time: base.Add(time.Minute + time.Second), CPUPercent: 2},
+				{ContainerID: "a", Time: base, CPUPercent: 1},
+			},
+			expected: []ContainerStatsSample{
+				{ContainerID: "a", Time: base, Samples: 1, CPUPercent: 1},
+				{ContainerID: "a", Time: base.Add(time.Minute), Samples: 1, CPUPercent: 2},
+				{ContainerID: "b", Time: base.Add(time.Minute), Samples: 1, CPUPercent: 4},
+			},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		aggregates := AggregateStatsSamples(tt.samples, time.Minute, StatsResolutionMinute)
+		if len(aggregates) != len(tt.expected) {
+			t.Errorf("%s: got %d aggregates, want %d", tt.name, len(aggregates), len(tt.expected))
+			continue
+		}
+		for i, want := range tt.expected {
+			got := aggregates[i]
+			want.Resolution = StatsResolutionMinute
+			// averages are compared with a tolerance
+			if math.Abs(got.CPUPercent-want.CPUPercent) > 1e-9 || math.Abs(got.NetworkRxRate-want.NetworkRxRate) > 1e-9 {
+				t.Errorf("%s[%d]: cpu %v rx %v, want %v %v", tt.name, i, got.CPUPercent, got.NetworkRxRate, want.CPUPercent, want.NetworkRxRate)
+			}
+			got.CPUPercent, got.NetworkRxRate = want.CPUPercent, want.NetworkRxRate
+			if got != want {
+				t.Errorf("%s[%d]: got %+v, want %+v", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestStatsPruneRemoved(t *testing.T) {
+	s := &StatsManager{db: memoryDB(t, &ContainerStatsSample{})}
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-2 * statsRemovedRetention)
+	err := s.SaveSamples([]ContainerStatsSample{
+		{ContainerID: "running", Resolution: StatsResolutionHour, Time: old},
+		{ContainerID: "removed", Resolution: StatsResolutionHour, Time: old},
+		{ContainerID: "recent", Resolution: StatsResolutionRaw, Time: now.Add(-time.Minute)},
+		{ContainerID: "recent", Resolution: StatsResolutionHour, Time: old},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PruneRemoved([]string{"running"}, now); err != nil {
+		t.Fatal(err)
+	}
+	ids, err := s.ContainerIDs("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] == "removed" || ids[1] == "removed" {
+		t.Errorf("got %v, want running and recent", ids)
+	}
+}
+
+func TestStatsContainerIDs(t *testing.T) {
+	s := &StatsManager{db: memoryDB(t, &ContainerStatsSample{})}
+	err := s.SaveSamples([]ContainerStatsSample{
+		{ContainerID: "abc123", Time: time.Now()},
+		{ContainerID: "abd456", Time: time.Now()},
+		{ContainerID: "abc123", Time: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		prefix   string
+		expected int
+	}{
+		{prefix: "abc", expected: 1},
+		{prefix: "ab", expected: 2},
+		{prefix: "abc123", expected: 1},
+		{prefix: "ab_", expected: 0},
+		{prefix: "%", expected: 0},
+		{prefix: "x", expected: 0},
+	}
+	for _, tt := range tests {
+		ids, err := s.ContainerIDs(tt.prefix)
+		if err != nil {
+			t.Errorf("%q: %s", tt.prefix, err.Error())
+			continue
+		}
+		if len(ids) != tt.expected {
+			t.Errorf("%q: got %v, want %d ids", tt.prefix, ids, tt.expected)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package types
 
 import (
+	"time"
+
 	"github.com/docker/docker/api/types/strslice"
 	"github.com/zishang520/socket.io/socket"
 )
@@ -39,22 +41,49 @@ type ContainerGetParams struct {
 	Name strslice.StrSlice `json:"name omitempty"`
 }
 type ContainerCreateParams struct {
-	Image          string            `binding:"required"`
-	Name           string            `json:"name default=''"`
-	Cmd            strslice.StrSlice `json:"cmd omitempty"`
-	Tty            bool              `json:"tty"`
-	Stdin          bool              `json:"stdin"`
-	Stdout         bool              `json:"stdout"`
-	Stderr         bool              `json:"stderr"`
-	Detach         bool              `json:"detach"`
-	Interactive    bool              `json:"interactive"`
-	WorkingDir     string            `json:"working_dir omitempty"`
-	User           string            `json:"user omitempty"`
-	Entrypoint     strslice.StrSlice `json:"entrypoint omitempty"`
-	Autoremove     bool              `json:"auto_remove"`
-	ExposeAllPorts bool              `json:"expose_all_ports"`
-	Env            strslice.StrSlice `json:"env omitempty"`
-	Shell          strslice.StrSlice `json:"shell omitempty"`
+	Image          string                 `json:"image" binding:"required"`
+	Name           string                 `json:"name"`
+	Cmd            strslice.StrSlice      `json:"cmd,omitempty"`
+	Tty            bool                   `json:"tty"`
+	Stdin          bool                   `json:"stdin"`
+	Stdout         bool                   `json:"stdout"`
+	Stderr         bool                   `json:"stderr"`
+	Detach         bool                   `json:"detach"`
+	Interactive    bool                   `json:"interactive"`
+	WorkingDir     string                 `json:"working_dir,omitempty"`
+	User           string                 `json:"user,omitempty"`
+	Entrypoint     strslice.StrSlice      `json:"entrypoint,omitempty"`
+	Autoremove     bool                   `json:"auto_remove"`
+	ExposeAllPorts bool                   `json:"expose_all_ports"`
+	Env            strslice.StrSlice      `json:"env,omitempty"`
+	Shell          strslice.StrSlice      `json:"shell,omitempty"`
+	Labels         map[string]string      `json:"labels,omitempty"`
+	Ports          []ContainerPortBinding `json:"ports,omitempty"`
+	Mounts         []ContainerMount       `json:"mounts,omitempty"`
+	RestartPolicy  string                 `json:"restart_policy,omitempty"`
+	Memory         string                 `json:"memory,omitempty"`
+	MemorySwap     string                 `json:"memory_swap,omitempty"`
+	Cpus           float64                `json:"cpus,omitempty"`
+	CpuShares      int64                  `json:"cpu_shares,omitempty"`
+	CapAdd         strslice.StrSlice      `json:"cap_add,omitempty"`
+	CapDrop        strslice.StrSlice      `json:"cap_drop,omitempty"`
+	Privileged     bool                   `json:"privileged"`
+	ExtraHosts     []string               `json:"extra_hosts,omitempty"`
+	Dns            []string               `json:"dns,omitempty"`
+	DnsSearch      []string               `json:"dns_search,omitempty"`
+	Network        string                 `json:"network,omitempty"`
+	NetworkAliases []string               `json:"network_aliases,omitempty"`
+}
+type ContainerPortBinding struct {
+	ContainerPort string `json:"container_port"`
+	HostPort      string `json:"host_port"`
+	HostIP        string `json:"host_ip"`
+}
+type ContainerMount struct {
+	Type     string `json:"type"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only"`
 }
 type ContainerRunParams struct {
 	Image  string `binding:"required"`
@@ -77,29 +106,129 @@ type ContainerTopParams struct {
 type ContainerStatsParams struct {
 	CommonRequestParams
 }
+type ContainerStats struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Read          time.Time `json:"read"`
+	CPUPercent    float64   `json:"cpu_percent"`
+	OnlineCPUs    uint32    `json:"online_cpus"`
+	MemoryUsage   uint64    `json:"memory_usage"`
+	MemoryLimit   uint64    `json:"memory_limit"`
+	MemoryPercent float64   `json:"memory_percent"`
+	NetworkRx     uint64    `json:"network_rx"`
+	NetworkTx     uint64    `json:"network_tx"`
+	NetworkRxRate float64   `json:"network_rx_rate"`
+	NetworkTxRate float64   `json:"network_tx_rate"`
+	BlockRead     uint64    `json:"block_read"`
+	BlockWrite    uint64    `json:"block_write"`
+	PIDs          uint64    `json:"pids"`
+}
+type ContainerStatsHistoryQuery struct {
+	From       string `form:"from"`
+	To         string `form:"to"`
+	Resolution string `form:"resolution"`
+}
 type ContainerDiffParams struct {
 	CommonRequestParams
 }
 type ContainerExportParams struct {
 	ID string `uri:"id" binding:"required"`
 }
+type ContainerArchiveQuery struct {
+	Path                    string `form:"path" binding:"required"`
+	Raw                     bool   `form:"raw"`
+	AllowOverwriteDirNonDir bool   `form:"allow_overwrite_dir_non_dir"`
+	CopyUIDGID              bool   `form:"copy_uid_gid"`
+}
+type ContainerFilesQuery struct {
+	Path  string `form:"path"`
+	Limit int64  `form:"limit"`
+}
+type FileEntry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	Type       string    `json:"type"`
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	Mtime      time.Time `json:"mtime"`
+	LinkTarget string    `json:"link_target,omitempty"`
+}
+type FileListing struct {
+	Path      string       `json:"path"`
+	Entry     *FileEntry   `json:"entry"`
+	Entries   []*FileEntry `json:"entries"`
+	Truncated bool         `json:"truncated"`
+}
+type FilePreview struct {
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Truncated bool   `json:"truncated"`
+	Encoding  string `json:"encoding"`
+	Content   string `json:"content"`
+}
 type ContainerLogsParams struct {
 	CommonRequestParams
 }
 type ContainerLogsQuery struct {
-	Tail       string `uri:"tail omitempty"`
-	Follow     bool   `uri:"follow omitempty"`
-	Since      string `uri:"since omitempty"`
-	Until      string `uri:"until omitempty"`
-	Timestamps bool   `uri:"timestamps omitempty"`
-	Details    bool   `uri:"details omitempty"`
-	ShowStdout bool   `uri:"stdout omitempty"`
-	ShowStderr bool   `uri:"stderr omitempty"`
+	Tail       string `form:"tail"`
+	Follow     bool   `form:"follow"`
+	Since      string `form:"since"`
+	Until      string `form:"until"`
+	Timestamps bool   `form:"timestamps"`
+	Details    bool   `form:"details"`
+	ShowStdout bool   `form:"stdout"`
+	ShowStderr bool   `form:"stderr"`
 }
 type ContainerRemoveParams struct {
 	CommonRequestParams
 	Force bool `json:"force"`
 }
+type PruneParams struct {
+	Until         string   `json:"until"`
+	Labels        []string `json:"labels"`
+	ExcludeLabels []string `json:"exclude_labels"`
+	DryRun        bool     `json:"dry_run"`
+}
+type PruneReport struct {
+	DryRun         bool     `json:"dry_run"`
+	Deleted        []string `json:"deleted"`
+	Untagged       []string `json:"untagged,omitempty"`
+	SpaceReclaimed uint64   `json:"space_reclaimed"`
+}
+type ImagePruneParams struct {
+	PruneParams
+	Dangling *bool `json:"dangling"`
+}
+type ImageTagParams struct {
+	Repo string `json:"repo" binding:"required"`
+	Tag  string `json:"tag"`
+}
+type ImageUntagParams struct {
+	Ref string `json:"ref" binding:"required"`
+}
+type ImageRemoveQuery struct {
+	Force   bool `form:"force"`
+	NoPrune bool `form:"noprune"`
+}
+type ImageSaveQuery struct {
+	Refs []string `form:"refs" binding:"required"`
+}
+type ImageImportQuery struct {
+	Repo     string   `form:"repo"`
+	Tag      string   `form:"tag"`
+	Message  string   `form:"message"`
+	Changes  []string `form:"changes"`
+	Platform string   `form:"platform"`
+}
+type ContainerCommitParams struct {
+	Repo    string   `json:"repo"`
+	Tag     string   `json:"tag"`
+	Author  string   `json:"author"`
+	Message string   `json:"message"`
+	Pause   *bool    `json:"pause"`
+	Changes []string `json:"changes"`
+}
 type ContainerExecParams struct {
 	ID string `uri:"id" binding:"required"`
 }
@@ -121,13 +250,42 @@ type ContainerExecBody struct {
 	Privileged  bool   `json:"privileged"`
 	User        string `json:"user"`
 }
+type ContainerTerminalParams struct {
+	ID         string            `json:"id"`
+	Cmd        strslice.StrSlice `json:"cmd"`
+	User       string            `json:"user"`
+	WorkingDir string            `json:"working_dir"`
+	Env        strslice.StrSlice `json:"env"`
+	NoTty      bool              `json:"no_tty"`
+	Rows       uint              `json:"rows"`
+	Cols       uint              `json:"cols"`
+}
+type ContainerTerminalInputParams struct {
+	ExecID string `json:"exec_id"`
+	Data   string `json:"data"`
+}
+type ContainerTerminalResizeParams struct {
+	ExecID string `json:"exec_id"`
+	Rows   uint   `json:"rows"`
+	Cols   uint   `json:"cols"`
+}
+type StreamFrame struct {
+	ID     string `json:"id"`
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+}
 type ContainerAttachParams struct {
 	CommonRequestParams
-	Stdout bool `json:"stdout"`
-	Stderr bool `json:"stderr"`
-	Stdin  bool `json:"stdin"`
-	Stream bool `json:"stream"`
-	Logs   bool `json:"logs"`
+	Stdout     bool   `json:"stdout"`
+	Stderr     bool   `json:"stderr"`
+	Stdin      bool   `json:"stdin"`
+	Stream     bool   `json:"stream"`
+	Logs       bool   `json:"logs"`
+	DetachKeys string `json:"detach_keys"`
+}
+type ContainerAttachInputParams struct {
+	ID   string `json:"id"`
+	Data string `json:"data"`
 }
 type ContainerSummary struct {
 	ID      string `json:"id"`
@@ -148,23 +306,130 @@ type ImagePullParams struct {
 	Repo string `json:"repo" binding:"required"`
 	Tag  string `json:"tag"`
 }
+type ImagePushParams struct {
+	Repo string `json:"repo" binding:"required"`
+	Tag  string `json:"tag"`
+}
 type ImagePullProgress struct {
-	Status string `json:"status"`
+	JobID   string  `json:"job_id"`
+	Layer   string  `json:"layer"`
+	Status  string  `json:"status"`
+	Current int64   `json:"current"`
+	Total   int64   `json:"total"`
+	Percent float64 `json:"percent"`
+}
+type Job struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	Ref        string     `json:"ref"`
+	Status     string     `json:"status"`
+	Progress   float64    `json:"progress"`
+	Result     string     `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+type JobRequestParams struct {
+	CommonRequestParams
+}
+type ImageBuildLog struct {
+	JobID   string `json:"job_id"`
+	Step    string `json:"step,omitempty"`
+	Stream  string `json:"stream,omitempty"`
+	Error   string `json:"error,omitempty"`
+	ImageID string `json:"image_id,omitempty"`
 }
 type ImageBuildParams struct {
-	Tag string `form:"tag omitempty"`
+	Tag        string   `form:"tag"`
+	Tags       []string `form:"tags"`
+	BuildArgs  []string `form:"build_args"`
+	Labels     []string `form:"labels"`
+	Target     string   `form:"target"`
+	Dockerfile string   `form:"dockerfile"`
+	Platform   string   `form:"platform"`
+	NoCache    bool     `form:"no_cache"`
+	PullParent bool     `form:"pull_parent"`
+
+	DockerfileContent string   `form:"dockerfile_content"`
+	Dockerignore      string   `form:"dockerignore"`
+	Paths             []string `form:"paths"`
+}
+type VolumeContainer struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Destination string `json:"destination"`
+	ReadOnly    bool   `json:"read_only"`
 }
 type VolumeSummary struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	Created    string `json:"created"`
-	MountPoint string `json:"mount_point"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Created    string            `json:"created"`
+	MountPoint string            `json:"mount_point"`
+	Driver     string            `json:"driver"`
+	Scope      string            `json:"scope"`
+	Labels     map[string]string `json:"labels"`
+	Containers []VolumeContainer `json:"containers"`
+}
+type VolumeCreateParams struct {
+	Name       string            `json:"name"`
+	Driver     string            `json:"driver"`
+	DriverOpts map[string]string `json:"driver_opts"`
+	Labels     map[string]string `json:"labels"`
+}
+type VolumeRemoveQuery struct {
+	Force bool `form:"force"`
+}
+type VolumePruneParams struct {
+	PruneParams
+	All bool `json:"all"`
+}
+type NetworkIPAMConfig struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway"`
+	IPRange string `json:"ip_range"`
+}
+type NetworkCreateParams struct {
+	Name       string              `json:"name" binding:"required"`
+	Driver     string              `json:"driver"`
+	IPAM       []NetworkIPAMConfig `json:"ipam"`
+	EnableIPv6 bool                `json:"enable_ipv6"`
+	Internal   bool                `json:"internal"`
+	Attachable bool                `json:"attachable"`
+	Labels     map[string]string   `json:"labels"`
+	Options    map[string]string   `json:"options"`
+}
+type NetworkConnectParams struct {
+	Container   string   `json:"container" binding:"required"`
+	Aliases     []string `json:"aliases"`
+	IPv4Address string   `json:"ipv4_address"`
+	IPv6Address string   `json:"ipv6_address"`
+}
+type NetworkDisconnectParams struct {
+	Container string `json:"container" binding:"required"`
+	Force     bool   `json:"force"`
+}
+type NetworkSubnet struct {
+	Subnet  string `json:"subnet"`
+	Gateway string `json:"gateway"`
+}
+type NetworkContainer struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	IPv4Address string `json:"ipv4_address"`
+	IPv6Address string `json:"ipv6_address"`
+	MacAddress  string `json:"mac_address"`
 }
 type NetworkSummary struct {
-	ID      string   `json:"id"`
-	Name    string   `json:"name"`
-	Created string   `json:"created"`
-	Ports   []string `json:"ports"`
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Created    string             `json:"created"`
+	Driver     string             `json:"driver"`
+	Scope      string             `json:"scope"`
+	Internal   bool               `json:"internal"`
+	Attachable bool               `json:"attachable"`
+	EnableIPv6 bool               `json:"enable_ipv6"`
+	Subnets    []NetworkSubnet    `json:"subnets"`
+	Containers []NetworkContainer `json:"containers"`
 }
 
 type ContainerActionResult struct {
@@ -195,3 +460,149 @@ type ConnectionTestParams struct {
 	Connection string `json:"connection"`
 	Exact      bool   `json:"exact"`
 }
+type ImageLayer struct {
+	ID          string    `json:"id"`
+	Created     time.Time `json:"created"`
+	CreatedBy   string    `json:"created_by"`
+	Instruction string    `json:"instruction"`
+	Command     string    `json:"command"`
+	Comment     string    `json:"comment"`
+	Tags        []string  `json:"tags"`
+	Size        int64     `json:"size"`
+	Cumulative  int64     `json:"cumulative"`
+	Percent     float64   `json:"percent"`
+	Empty       bool      `json:"empty"`
+}
+type ImageInstructionSize struct {
+	Instruction string  `json:"instruction"`
+	Layers      int     `json:"layers"`
+	Size        int64   `json:"size"`
+	Percent     float64 `json:"percent"`
+}
+type ImageHistory struct {
+	ID           string                 `json:"id"`
+	Size         int64                  `json:"size"`
+	Layers       []ImageLayer           `json:"layers"`
+	Largest      []ImageLayer           `json:"largest"`
+	Instructions []ImageInstructionSize `json:"instructions"`
+}
+type ImageLayerRequestParams struct {
+	CommonRequestParams
+	Index *int `uri:"index" binding:"required,min=0"`
+}
+type ImageLayerFilesQuery struct {
+	Path   string `form:"path"`
+	Change string `form:"change"`
+	Limit  int    `form:"limit"`
+}
+type ImageLayerSummary struct {
+	Index        int    `json:"index"`
+	Digest       string `json:"digest"`
+	CreatedBy    string `json:"created_by"`
+	Instruction  string `json:"instruction"`
+	Command      string `json:"command"`
+	Size         int64  `json:"size"`
+	Files        int    `json:"files"`
+	Added        int    `json:"added"`
+	Modified     int    `json:"modified"`
+	Deleted      int    `json:"deleted"`
+	AddedSize    int64  `json:"added_size"`
+	ModifiedSize int64  `json:"modified_size"`
+	DeletedSize  int64  `json:"deleted_size"`
+}
+type ImageWastedFile struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+	Size  int64  `json:"size"`
+}
+type ImageLayerReport struct {
+	ID         string              `json:"id"`
+	Size       int64               `json:"size"`
+	WastedSize int64               `json:"wasted_size"`
+	Efficiency float64             `json:"efficiency"`
+	Layers     []ImageLayerSummary `json:"layers"`
+	Wasted     []ImageWastedFile   `json:"wasted"`
+}
+type ImageFileChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Type   string `json:"type"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode,omitempty"`
+}
+type ImageLayerFiles struct {
+	Layer     ImageLayerSummary `json:"layer"`
+	Changes   []ImageFileChange `json:"changes"`
+	Total     int               `json:"total"`
+	Truncated bool              `json:"truncated"`
+}
+type ImageDiffQuery struct {
+	A     string `form:"a" binding:"required"`
+	B     string `form:"b" binding:"required"`
+	Path  string `form:"path"`
+	Limit int    `form:"limit"`
+}
+type ImagePathDiff struct {
+	Path      string `json:"path"`
+	Change    string `json:"change"`
+	Type      string `json:"type"`
+	SizeA     int64  `json:"size_a"`
+	SizeB     int64  `json:"size_b"`
+	SizeDelta int64  `json:"size_delta"`
+	ModeA     string `json:"mode_a,omitempty"`
+	ModeB     string `json:"mode_b,omitempty"`
+}
+type ImageValueChange struct {
+	Key string `json:"key"`
+	A   string `json:"a"`
+	B   string `json:"b"`
+}
+type ImageKeyedDiff struct {
+	Added   map[string]string  `json:"added"`
+	Removed map[string]string  `json:"removed"`
+	Changed []ImageValueChange `json:"changed"`
+}
+type ImageListDiff struct {
+	A       []string `json:"a"`
+	B       []string `json:"b"`
+	Changed bool     `json:"changed"`
+}
+type ImageSetDiff struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+type ImageConfigDiff struct {
+	Env          ImageKeyedDiff `json:"env"`
+	Cmd          ImageListDiff  `json:"cmd"`
+	Entrypoint   ImageListDiff  `json:"entrypoint"`
+	ExposedPorts ImageSetDiff   `json:"exposed_ports"`
+	Labels       ImageKeyedDiff `json:"labels"`
+}
+type ImageDiff struct {
+	A         string          `json:"a"`
+	B         string          `json:"b"`
+	Added     int             `json:"added"`
+	Removed   int             `json:"removed"`
+	Changed   int             `json:"changed"`
+	SizeDelta int64           `json:"size_delta"`
+	Paths     []ImagePathDiff `json:"paths"`
+	Total     int             `json:"total"`
+	Truncated bool            `json:"truncated"`
+	Config    ImageConfigDiff `json:"config"`
+}
+type TopologyNode struct {
+	ID   string            `json:"id"`
+	Kind string            `json:"kind"`
+	Name string            `json:"name"`
+	Meta map[string]string `json:"meta"`
+}
+type TopologyEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind"`
+	Label  string `json:"label"`
+}
+type Topology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+}
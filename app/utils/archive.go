@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// SingleFileArchive wraps the content of a single file in a tar stream so it
+// can be handed to the docker archive APIs. The archive is produced lazily as
+// the returned reader is consumed. The reader has to be closed, which also
+// closes content if it is a Closer, or the goroutine writing it is left
+// blocked when the consumer stops early.
+func SingleFileArchive(name string, size int64, mode int64, content io.Reader) (io.ReadCloser, error) {
+	base := path.Base(name)
+	if base == "." || base == ".." || base == "/" {
+		return nil, fmt.Errorf("invalid file name %q", name)
+	}
+	if mode == 0 {
+		mode = 0644
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     base,
+			Size:     size,
+			Mode:     mode,
+			ModTime:  time.Now(),
+		})
+		if err == nil {
+			_, err = io.Copy(tw, content)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return &archiveReader{PipeReader: pr, content: content}, nil
+}
+
+type archiveReader struct {
+	*io.PipeReader
+	content io.Reader
+}
+
+func (r *archiveReader) Close() error {
+	err := r.PipeReader.Close()
+	if c, ok := r.content.(io.Closer); ok {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// FirstFileInArchive advances the tar stream to its first regular file and
+// returns its header. The file content can then be read from tr.
+func FirstFileInArchive(tr *tar.Reader) (*tar.Header, error) {
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("archive does not contain a regular file")
+			}
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			return hdr, nil
+		}
+	}
+}
+
+type removeOnClose struct {
+	*os.File
+	dir string
+}
+
+func (r *removeOnClose) Close() error {
+	err := r.File.Close()
+	os.RemoveAll(r.dir)
+	return err
+}
+
+// RemoveOnClose returns f as a ReadCloser that also deletes the temporary
+// directory dir once it is closed.
+func RemoveOnClose(f *os.File, dir string) io.ReadCloser {
+	return &removeOnClose{File: f, dir: dir}
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"archive/tar"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// endless never runs out, so the writer only stops when the archive is closed
+type endless struct {
+	closed bool
+}
+
+func (e *endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+func (e *endless) Close() error {
+	e.closed = true
+	return nil
+}
+
+func TestSingleFileArchive(t *testing.T) {
+	rc, err := SingleFileArchive("dir/hello.txt", 5, 0, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	tr := tar.NewReader(rc)
+	hdr, err := FirstFileInArchive(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "hello.txt" || hdr.Size != 5 || hdr.Mode != 0644 {
+		t.Errorf("unexpected header %s %d %o", hdr.Name, hdr.Size, hdr.Mode)
+	}
+	content, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello" {
+		t.Errorf("got content %q", content)
+	}
+}
+
+func TestSingleFileArchiveInvalidName(t *testing.T) {
+	for _, name := range []string{"", "/", ".."} {
+		if _, err := SingleFileArchive(name, 0, 0, strings.NewReader("")); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+}
+
+func TestSingleFileArchiveCloseEarly(t *testing.T) {
+	before := runtime.NumGoroutine()
+	content := &endless{}
+	rc, err := SingleFileArchive("big", 1<<40, 0, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// read a little so the writer is blocked on the pipe, then give up
+	if _, err := io.ReadFull(rc, make([]byte, 4096)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !content.closed {
+		t.Error("closing the archive did not close its content")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("writer goroutine still running: %d goroutines, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// ParseDockerignore reads .dockerignore content into a matcher that applies
+// the same rules as the docker CLI when it sends a build context: "**"
+// matches any number of directories, "!" re-includes a path, the last
+// matching pattern wins and a pattern matching a directory also matches
+// everything below it. Paths are matched with MatchesOrParentMatches.
+func ParseDockerignore(r io.Reader) (*patternmatcher.PatternMatcher, error) {
+	patterns, err := ignorefile.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("dockerignore: %s", err.Error())
+	}
+	pm, err := patternmatcher.New(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("dockerignore: %s", err.Error())
+	}
+	return pm, nil
+}
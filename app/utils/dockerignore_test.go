@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDockerignore(t *testing.T) {
+	tests := []struct {
+		name     string
+		ignore   string
+		path     string
+		expected bool
+	}{
+		// the example from the .dockerignore reference
+		{"comment", "# comment\n*/temp*", "# comment", false},
+		{"one level", "*/temp*", "somedir/temporary.txt", true},
+		{"one level dir", "*/temp*", "somedir/temp/file", true},
+		{"one level root", "*/temp*", "temporary.txt", false},
+		{"two levels", "*/*/temp*", "somedir/subdir/temporary.txt", true},
+		{"single char", "temp?", "tempa", true},
+		{"single char too long", "temp?", "tempab", false},
+
+		{"any depth", "**/*.go", "a/b/c/main.go", true},
+		{"any depth root", "**/*.go", "main.go", true},
+		{"any depth other", "**/*.go", "a/b/main.py", false},
+		{"directory", "build", "build/out/app", true},
+		{"leading slash", "/build", "build/app", true},
+		{"re-include", "*.md\n!README.md", "README.md", false},
+		{"re-include other", "*.md\n!README.md", "CHANGES.md", true},
+		{"last match wins", "*.md\n!README*.md\nREADME-secret.md", "README-secret.md", true},
+		{"last match wins include", "*.md\n!README*.md\nREADME-secret.md", "README-public.md", false},
+		// classes follow filepath.Match, negation is "^" and "!" is literal
+		{"negated class", "[^a]bc", "bbc", true},
+		{"negated class match", "[^a]bc", "abc", false},
+		{"bang in class", "[!a]bc", "!bc", true},
+		{"bang in class a", "[!a]bc", "abc", true},
+		{"bang in class other", "[!a]bc", "bbc", false},
+		{"class range", "file[0-9]", "file7", true},
+		{"escaped star", `\*.txt`, "*.txt", true},
+		{"escaped star literal", `\*.txt`, "a.txt", false},
+		{"escaped class", `\[ab\]`, "[ab]", true},
+		{"escaped class literal", `\[ab\]`, "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, err := ParseDockerignore(strings.NewReader(tt.ignore))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ignored, err := pm.MatchesOrParentMatches(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ignored != tt.expected {
+				t.Errorf("%q with %q: got %v, want %v", tt.path, tt.ignore, ignored, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDockerignoreInvalid(t *testing.T) {
+	for _, ignore := range []string{"!", "[a-"} {
+		if _, err := ParseDockerignore(strings.NewReader(ignore)); err == nil {
+			t.Errorf("%q: expected an error", ignore)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const secretKeySize = 32
+
+// GetSecretKey returns the key secrets are encrypted with at rest. It is
+// generated on first use and kept next to the database in the config path,
+// readable only by the current user.
+func (c *ConfigurationManager) GetSecretKey() ([]byte, error) {
+	p := fmt.Sprintf("%s/secret.key", c.GetConfigPath())
+	key, err := os.ReadFile(p)
+	if err == nil {
+		if len(key) != secretKeySize {
+			return nil, fmt.Errorf("%s: expected a %d byte key", p, secretKeySize)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+	key = make([]byte, secretKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	// O_EXCL so two processes starting at once cannot end up with different keys
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return c.GetSecretKey()
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Write(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Encrypt seals plaintext with AES-GCM and returns the nonce and ciphertext
+// base64 encoded.
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt
+func Decrypt(key []byte, ciphertext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("ciphertext is too short")
+	}
+	nonce, sealed := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// RegistryHost reduces a registry address such as "https://index.docker.io/v1/"
+// or "localhost:5000/v2" to the host credentials are looked up by. The
+// various Docker Hub hosts all map to "docker.io".
+func RegistryHost(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if strings.Contains(addr, "://") {
+		if u, err := url.Parse(addr); err == nil {
+			addr = u.Host
+		}
+	}
+	addr, _, _ = strings.Cut(addr, "/")
+	addr = strings.ToLower(addr)
+	switch addr {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return "docker.io"
+	}
+	return addr
+}
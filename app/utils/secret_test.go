@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "", expected: ""},
+		{addr: "   ", expected: ""},
+		{addr: "docker.io", expected: "docker.io"},
+		{addr: "index.docker.io", expected: "docker.io"},
+		{addr: "https://index.docker.io/v1/", expected: "docker.io"},
+		{addr: "registry-1.docker.io", expected: "docker.io"},
+		{addr: "Registry.Hub.Docker.com", expected: "docker.io"},
+		{addr: "ghcr.io", expected: "ghcr.io"},
+		{addr: "ghcr.io/owner/app", expected: "ghcr.io"},
+		{addr: "localhost:5000", expected: "localhost:5000"},
+		{addr: "localhost:5000/v2", expected: "localhost:5000"},
+		{addr: "http://LOCALHOST:5000/v2/", expected: "localhost:5000"},
+		{addr: " registry.example.com:443 ", expected: "registry.example.com:443"},
+		{addr: "https://", expected: ""},
+	}
+	for _, tt := range tests {
+		if host := RegistryHost(tt.addr); host != tt.expected {
+			t.Errorf("%q: got %q, want %q", tt.addr, host, tt.expected)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, secretKeySize)
+	sealed, err := Encrypt(key, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Encrypt(key, "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed == again {
+		t.Error("encrypting twice gave the same ciphertext")
+	}
+	plaintext, err := Decrypt(key, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext != "hunter2" {
+		t.Errorf("got %q", plaintext)
+	}
+	if _, err := Decrypt(bytes.Repeat([]byte{8}, secretKeySize), sealed); err == nil {
+		t.Error("decrypting with another key should fail")
+	}
+	if _, err := Decrypt(key, "not base64!"); err == nil {
+		t.Error("decrypting garbage should fail")
+	}
+	if _, err := Decrypt(key, ""); err == nil {
+		t.Error("decrypting nothing should fail")
+	}
+}
@@ -1,15 +1,24 @@
 package utils
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 const APP_NAME string = "reactor"
+const DEFAULT_DETACH_KEYS string = "ctrl-p,ctrl-q"
+const DEFAULT_VOLUME_HELPER_IMAGE string = "busybox:latest"
 
 var defaultConfiguration *ConfigurationManager
 
@@ -62,9 +71,112 @@ func (c *ConfigurationManager) GetLogPath() string {
 	l := fmt.Sprintf("%s/logs", cp)
 	return l
 }
+
+// GetVolumeHelperImage returns the image of the helper containers volume files
+// are accessed through, set with REACTOR_VOLUME_HELPER_IMAGE. Any image will
+// do as the helpers are never started, so hosts without access to Docker Hub
+// can point it at an image that is already present.
+func (c *ConfigurationManager) GetVolumeHelperImage() string {
+	if image := os.Getenv("REACTOR_VOLUME_HELPER_IMAGE"); image != "" {
+		return image
+	}
+	return DEFAULT_VOLUME_HELPER_IMAGE
+}
+
+// GetInstanceID returns an ID that tells this installation apart from others
+// sharing the same daemon. Like the secret key it is generated on first use
+// and kept in the config path.
+func (c *ConfigurationManager) GetInstanceID() (string, error) {
+	p := fmt.Sprintf("%s/instance.id", c.GetConfigPath())
+	id, err := os.ReadFile(p)
+	if err == nil {
+		if len(bytes.TrimSpace(id)) == 0 {
+			return "", fmt.Errorf("%s: is empty", p)
+		}
+		return string(bytes.TrimSpace(id)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// O_EXCL so two processes starting at once cannot end up with different IDs
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return c.GetInstanceID()
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(hex.EncodeToString(b)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
 func BadRequestError(ctx *gin.Context, err error) {
 	if err == nil {
 		return
 	}
 	ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 }
+
+// DecodeRecord converts a loosely typed socket.io payload into the given
+// struct by round-tripping it through its json tags.
+func DecodeRecord(record any, out any) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// ParseDetachKeys converts a docker style detach key sequence such as
+// "ctrl-p,ctrl-q" into the bytes the daemon expects on stdin.
+func ParseDetachKeys(keys string) ([]byte, error) {
+	codes := make([]byte, 0)
+	for _, key := range strings.Split(keys, ",") {
+		if len(key) == 1 {
+			codes = append(codes, key[0])
+			continue
+		}
+		name, k, found := strings.Cut(strings.ToLower(key), "-")
+		if !found || name != "ctrl" || len(k) != 1 {
+			return nil, fmt.Errorf("detach_keys: invalid key %q", key)
+		}
+		switch {
+		case k[0] >= 'a' && k[0] <= 'z':
+			codes = append(codes, k[0]-'a'+1)
+		case k == "@":
+			codes = append(codes, 0)
+		case k == "[":
+			codes = append(codes, 27)
+		case k == "\\":
+			codes = append(codes, 28)
+		case k == "]":
+			codes = append(codes, 29)
+		case k == "^":
+			codes = append(codes, 30)
+		case k == "_":
+			codes = append(codes, 31)
+		default:
+			return nil, fmt.Errorf("detach_keys: invalid key %q", key)
+		}
+	}
+	return codes, nil
+}
+
+// ParseTime accepts either an RFC 3339 timestamp or unix seconds.
+func ParseTime(value string) (time.Time, error) {
+	secs, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339 or unix seconds", value)
+	}
+	return t, nil
+}
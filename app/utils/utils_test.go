@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestParseDetachKeys(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected []byte
+		fails    bool
+	}{
+		{keys: "ctrl-p,ctrl-q", expected: []byte{16, 17}},
+		{keys: "CTRL-A", expected: []byte{1}},
+		{keys: "ctrl-z", expected: []byte{26}},
+		{keys: "ctrl-@,ctrl-[,ctrl-\\,ctrl-],ctrl-^,ctrl-_", expected: []byte{0, 27, 28, 29, 30, 31}},
+		{keys: "a,ctrl-b", expected: []byte{'a', 2}},
+		{keys: "x", expected: []byte{'x'}},
+		{keys: "", fails: true},
+		{keys: "ctrl-", fails: true},
+		{keys: "ctrl-ab", fails: true},
+		{keys: "alt-a", fails: true},
+		{keys: "ctrl-1", fails: true},
+		{keys: "ctrl-p,", fails: true},
+	}
+	for _, tt := range tests {
+		codes, err := ParseDetachKeys(tt.keys)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", tt.keys, codes)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tt.keys, err.Error())
+			continue
+		}
+		if !bytes.Equal(codes, tt.expected) {
+			t.Errorf("%q: got %v, want %v", tt.keys, codes, tt.expected)
+		}
+	}
+}
+
+func TestGetInstanceID(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	c := &ConfigurationManager{}
+	if err := os.MkdirAll(c.GetConfigPath(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	id, err := c.GetInstanceID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(id) != 32 {
+		t.Errorf("got %q, want 32 hex characters", id)
+	}
+	again, err := c.GetInstanceID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != id {
+		t.Errorf("id changed from %q to %q", id, again)
+	}
+}